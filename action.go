@@ -0,0 +1,174 @@
+package quadpix
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ActionConstructor creates an Action from the given parameters.
+//
+// Constructors are registered by name with RegisterActionConstructor so entities can reference
+// behaviours by name and parameters instead of by Go closures.
+type ActionConstructor func(params map[string]string) (Action, error)
+
+// Behaviour references a registered Action by its name and the parameters used to construct it.
+type Behaviour struct {
+	Name   string
+	Params map[string]string
+}
+
+// B creates a new Behaviour with the given name and parameters.
+//
+// params is read as key, value pairs and B panics if given an odd number of params.
+func B(name string, params ...string) Behaviour {
+	if len(params)%2 != 0 {
+		panic("quadpix: B called with an odd number of params for " + name)
+	}
+
+	b := Behaviour{
+		Name: name,
+	}
+
+	if len(params) > 0 {
+		b.Params = make(map[string]string, len(params)/2)
+		for i := 0; i < len(params); i += 2 {
+			b.Params[params[i]] = params[i+1]
+		}
+	}
+
+	return b
+}
+
+// Bind creates the Action for this Behaviour from its registered constructor.
+//
+// Bind returns ErrUnknownAction if no constructor is registered with the Behaviour's name.
+func (b Behaviour) Bind() (Action, error) {
+	registry.RLock()
+	constructor, ok := registry.constructors[b.Name]
+	fallback := registry.fallback
+	registry.RUnlock()
+
+	if !ok {
+		if fallback != nil {
+			return fallback(b)
+		}
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAction, b.Name)
+	}
+
+	action, err := constructor(b.Params)
+	if err != nil {
+		return nil, fmt.Errorf("behaviour %q: %w", b.Name, err)
+	}
+
+	return action, nil
+}
+
+// registry of named Action functions and constructors used when saving and loading trees.
+var registry = struct {
+	sync.RWMutex
+
+	constructors map[string]ActionConstructor
+	names        map[uintptr]string
+	fallback     func(b Behaviour) (Action, error)
+}{
+	constructors: make(map[string]ActionConstructor),
+	names:        make(map[uintptr]string),
+}
+
+// RegisterAction registers the given Action function under the given name.
+//
+// When a tree is saved each Action of an entity is stored by its registered name, and when a tree is loaded
+// the name is turned back in to the registered Action. Actions are matched by their function code, which means
+// closures made from the same function literal can not be told apart and only the first of them can be
+// registered. Closures that need their own data must be saved as Behaviours with RegisterActionConstructor.
+//
+// The name can also be used as a Behaviour with no parameters.
+//
+// RegisterAction panics if the name is empty, the action is nil, the name is already registered or the
+// action's function is already registered under another name.
+func RegisterAction(name string, action Action) {
+	if action == nil {
+		panic("quadpix: RegisterAction with nil action for " + name)
+	}
+
+	pointer := reflect.ValueOf(action).Pointer()
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if other, ok := registry.names[pointer]; ok {
+		panic(fmt.Sprintf("quadpix: action for %s is already registered as %s, use RegisterActionConstructor for closures", name, other))
+	}
+
+	register(name, func(map[string]string) (Action, error) {
+		return action, nil
+	})
+	registry.names[pointer] = name
+}
+
+// RegisterActionConstructor registers the given ActionConstructor under the given name.
+//
+// Entities that have a Behaviour with this name get their Action from the constructor when they are
+// inserted in to a tree or loaded from a saved tree.
+//
+// RegisterActionConstructor panics if the name is empty, the constructor is nil or the name is already registered.
+func RegisterActionConstructor(name string, constructor ActionConstructor) {
+	if constructor == nil {
+		panic("quadpix: RegisterActionConstructor with nil constructor for " + name)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	register(name, constructor)
+}
+
+// register adds the given constructor to the registry.
+//
+// The registry must be locked by the caller.
+func register(name string, constructor ActionConstructor) {
+	if name == "" {
+		panic("quadpix: register action with empty name")
+	}
+
+	if _, ok := registry.constructors[name]; ok {
+		panic("quadpix: action registered twice for " + name)
+	}
+
+	registry.constructors[name] = constructor
+}
+
+// SetActionFallback sets a function used to create the Action for any name that has not been registered.
+//
+// By default binding a name that has not been registered returns ErrUnknownAction. Tools that load saved trees
+// without the game's actions registered can set a fallback that returns a placeholder Action.
+// Passing nil removes the fallback. SetActionFallback returns the previous fallback so it can be put back.
+func SetActionFallback(fallback func(b Behaviour) (Action, error)) (previous func(b Behaviour) (Action, error)) {
+	registry.Lock()
+	previous, registry.fallback = registry.fallback, fallback
+	registry.Unlock()
+
+	return previous
+}
+
+// LookupAction returns the Action registered with the given name.
+//
+// For names registered with RegisterActionConstructor the constructor is called with no parameters.
+func LookupAction(name string) (Action, bool) {
+	action, err := Behaviour{Name: name}.Bind()
+	return action, err == nil
+}
+
+// actionName returns the registered name of the given Action.
+func actionName(action Action) (string, bool) {
+	if action == nil {
+		return "", false
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	name, ok := registry.names[reflect.ValueOf(action).Pointer()]
+	return name, ok
+}
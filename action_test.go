@@ -0,0 +1,236 @@
+package quadpix
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+// damage records the damage done by the "action-test-damage" behaviour.
+var damage int
+
+func init() {
+	RegisterActionConstructor("action-test-damage", func(params map[string]string) (Action, error) {
+		amount, err := strconv.Atoi(params["amount"])
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			damage += amount
+		}, nil
+	})
+}
+
+func TestB(t *testing.T) {
+	b := B("action-test-damage", "amount", "5")
+	if b.Name != "action-test-damage" || b.Params["amount"] != "5" {
+		t.Errorf("B() = %v, want name and amount param", b)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("B() with odd params did not panic")
+		}
+	}()
+	B("action-test-damage", "amount")
+}
+
+func TestEntity_Bind(t *testing.T) {
+	tests := []struct {
+		name       string
+		behaviours []Behaviour
+		wantDamage int
+		wantErr    error
+	}{
+		{
+			name:       "bind constructor",
+			behaviours: []Behaviour{B("action-test-damage", "amount", "3"), B("action-test-damage", "amount", "4")},
+			wantDamage: 7,
+		},
+		{
+			name:       "bind registered action",
+			behaviours: []Behaviour{B("json-test")},
+			wantDamage: 0,
+		},
+		{
+			name:       "unknown behaviour",
+			behaviours: []Behaviour{B("missing")},
+			wantErr:    ErrUnknownAction,
+		},
+		{
+			name:       "constructor error",
+			behaviours: []Behaviour{B("action-test-damage", "amount", "lots")},
+			wantErr:    strconv.ErrSyntax,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			damage = 0
+
+			tree := New(800, 600, 10, 4)
+			e := E(pixel.R(0, 0, 10, 10))
+			e.Behaviours = tt.behaviours
+
+			err := tree.InsertEntities(e)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Quadpix.InsertEntities() got error %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if <-tree.IsEntity(e) {
+					t.Errorf("Quadpix.InsertEntities() inserted entity with bad behaviours")
+				}
+				return
+			}
+
+			if len(e.Actions) != len(tt.behaviours) {
+				t.Fatalf("Entity.Bind() got %v actions, want %v", len(e.Actions), len(tt.behaviours))
+			}
+			for _, a := range e.Actions {
+				a()
+			}
+			if damage != tt.wantDamage {
+				t.Errorf("Entity.Bind() actions did %v damage, want %v", damage, tt.wantDamage)
+			}
+		})
+	}
+}
+
+func TestSetActionFallback(t *testing.T) {
+	placeholder := func(Behaviour) (Action, error) {
+		return func() {}, nil
+	}
+
+	previous := SetActionFallback(placeholder)
+	if _, err := B("action-test-missing").Bind(); err != nil {
+		t.Errorf("Behaviour.Bind() with fallback got error %v", err)
+	}
+
+	if restored := SetActionFallback(previous); restored == nil {
+		t.Errorf("SetActionFallback() did not return the fallback it replaced")
+	}
+	if _, err := B("action-test-missing").Bind(); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("Behaviour.Bind() after restoring got error %v, want %v", err, ErrUnknownAction)
+	}
+}
+
+func TestBehaviour_RoundTrip(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+
+	e := &Entity{ID: 1, Rect: pixel.R(0, 0, 50, 50)}
+	e.Behaviours = []Behaviour{B("action-test-damage", "amount", "9")}
+	if err := tree.InsertEntities(e, &Entity{ID: 2, Rect: pixel.R(10, 10, 20, 20), Actions: []Action{jsonTestAction}}); err != nil {
+		t.Fatalf("Quadpix.InsertEntities() got error %v", err)
+	}
+
+	jsonData, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Quadpix.MarshalJSON() got error %v", err)
+	}
+	binaryData, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Quadpix.MarshalBinary() got error %v", err)
+	}
+
+	loaders := map[string]func(q *Quadpix) error{
+		"json": func(q *Quadpix) error {
+			return json.Unmarshal(jsonData, q)
+		},
+		"binary": func(q *Quadpix) error {
+			return q.UnmarshalBinary(binaryData)
+		},
+	}
+	for name, load := range loaders {
+		t.Run(name, func(t *testing.T) {
+			got := new(Quadpix)
+			if err := load(got); err != nil {
+				t.Fatalf("load got error %v", err)
+			}
+
+			damage = 0
+			for _, entity := range got.all() {
+				if entity.ID == 1 && len(entity.Behaviours) != 1 {
+					t.Errorf("load did not restore behaviours for %v", entity)
+				}
+				for _, a := range entity.Actions {
+					a()
+				}
+			}
+			if damage != 9 {
+				t.Errorf("load did not re-bind behaviours, damage %v, want %v", damage, 9)
+			}
+		})
+	}
+}
+
+func TestQuadpix_UnmarshalBinaryVersion1(t *testing.T) {
+	// a version 1 snapshot has no behaviours in its entity table
+	e := &encoder{}
+	e.buf.WriteString(binaryMagic)
+	e.uint16(1)
+	e.uint16(0)
+	e.rect(pixel.R(0, 0, 10, 10))
+	e.uvarint(4)
+	e.uvarint(2)
+	e.uvarint(0)
+	e.uvarint(1)
+	e.uint64(7)
+	e.rect(pixel.R(1, 1, 2, 2))
+	e.uvarint(0)
+
+	got := new(Quadpix)
+	if err := got.UnmarshalBinary(e.buf.Bytes()); err != nil {
+		t.Fatalf("Quadpix.UnmarshalBinary() got error %v", err)
+	}
+	if !<-got.IsEntity(&Entity{ID: 7, Rect: pixel.R(1, 1, 2, 2)}) {
+		t.Errorf("Quadpix.UnmarshalBinary() entity not found")
+	}
+
+	// make sure the test data is not also a valid version 2 snapshot
+	data := e.buf.Bytes()
+	binary.LittleEndian.PutUint16(data[4:], 2)
+	if err := new(Quadpix).UnmarshalBinary(data); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Quadpix.UnmarshalBinary() as version 2 got error %v, want %v", err, ErrInvalidFormat)
+	}
+}
+
+// opened records the amount added by actions from actionTestClosure.
+var opened int
+
+// actionTestClosure returns a closure, so every Action it returns has the same function code.
+// It is not inlined as that would give each call site its own copy of the function literal.
+//
+//go:noinline
+func actionTestClosure(amount int) Action {
+	return func() {
+		opened += amount
+	}
+}
+
+func TestRegisterAction_Closures(t *testing.T) {
+	closure := actionTestClosure
+
+	RegisterAction("action-test-open", closure(1))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("RegisterAction() of a closure from the same function literal did not panic")
+			}
+		}()
+		RegisterAction("action-test-close", closure(2))
+	}()
+
+	// the first name is kept and the second name was not registered
+	names, err := actionNames(E(pixel.R(0, 0, 1, 1), closure(3)))
+	if err != nil || len(names) != 1 || names[0] != "action-test-open" {
+		t.Errorf("actionNames() = %v, %v, want [action-test-open]", names, err)
+	}
+	if _, err := B("action-test-close").Bind(); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("Behaviour.Bind() got error %v, want %v", err, ErrUnknownAction)
+	}
+}
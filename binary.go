@@ -0,0 +1,577 @@
+package quadpix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+
+	"github.com/faiface/pixel"
+)
+
+// Binary snapshot format.
+//
+// All fixed size values are little endian and all counts are unsigned varints.
+//
+//	header:   magic "QPIX", version uint16, flags uint16
+//	config:   root bounds 4 x float64, maxEntities, maxDepth.
+//	          Since version 3 followed by the looseness float64 and
+//	          since version 4 by the edge mode byte and epsilon float64
+//	strings:  count, then for each string its length and bytes
+//	entities: count, then for each entity its ID uint64, bounds 4 x float64,
+//	          action count and the string index of each action's name.
+//	          Since version 2 followed by a behaviour count and for each behaviour
+//	          the string index of its name, a parameter count and the string index
+//	          of each parameter's key and value
+//	nodes:    only if flagNodes is set. Each node in pre-order as its entity count,
+//	          the index of each entity and a child count of 0 or 4
+const (
+	binaryMagic   = "QPIX"
+	binaryVersion = 4
+
+	// flagNodes is set when the node structure is stored in the snapshot.
+	flagNodes uint16 = 1 << 0
+
+	// all flags known by this version of the format.
+	knownFlags = flagNodes
+)
+
+// smallest encoded size of an entity used to reject counts larger then the remaining data.
+const minEntitySize = 8 + 4*8 + 1
+
+// MarshalBinary encodes the tree in to the compact binary snapshot format.
+//
+// The node structure of the tree is included so loading the snapshot does not need to split any nodes.
+// Like MarshalJSON, Actions are stored by their registered name and ErrUnregisteredAction is returned
+// for any Action that has not been registered.
+func (q *Quadpix) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := q.WriteBinary(&buf, true); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the tree with the tree encoded in the given binary snapshot.
+//
+// UnmarshalBinary returns ErrInvalidFormat if the data is not a valid snapshot and ErrUnsupportedVersion
+// if the snapshot was written by a newer version of the format. On error the tree is left unchanged.
+func (q *Quadpix) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+
+	rect, cfg, entities, root, err := d.tree(q)
+	if err != nil {
+		return err
+	}
+
+	return q.load(rect, cfg, entities, root)
+}
+
+// WriteBinary writes the tree to w in the compact binary snapshot format.
+//
+// If nodes is true the node structure of the tree is written as well. This makes the snapshot larger
+// but lets ReadBinary rebuild the tree without inserting each entity again.
+func (q *Quadpix) WriteBinary(w io.Writer, nodes bool) error {
+	entities := q.all()
+
+	e := &encoder{}
+
+	// header
+	var flags uint16
+	if nodes {
+		flags |= flagNodes
+	}
+	e.buf.WriteString(binaryMagic)
+	e.uint16(binaryVersion)
+	e.uint16(flags)
+
+	// config
+	e.rect(q.rect)
+	e.uvarint(q.maxEntities)
+	e.uvarint(uint64(q.maxDepth))
+	e.uint64(math.Float64bits(q.looseness))
+	e.buf.WriteByte(byte(q.overlap.Edges))
+	e.uint64(math.Float64bits(q.overlap.Epsilon))
+
+	// string table of action names, behaviour names and parameters
+	strs := &stringTable{index: make(map[string]uint64)}
+	actions := make([][]uint64, len(entities))
+	for i, entity := range entities {
+		// entities with behaviours are stored by their behaviours only
+		if len(entity.Behaviours) > 0 {
+			for _, b := range entity.Behaviours {
+				strs.add(b.Name)
+				for k, v := range b.Params {
+					strs.add(k)
+					strs.add(v)
+				}
+			}
+			continue
+		}
+
+		entityNames, err := actionNames(entity)
+		if err != nil {
+			return err
+		}
+		for _, name := range entityNames {
+			actions[i] = append(actions[i], strs.add(name))
+		}
+	}
+
+	e.uvarint(uint64(len(strs.values)))
+	for _, str := range strs.values {
+		e.uvarint(uint64(len(str)))
+		e.buf.WriteString(str)
+	}
+
+	// entity table
+	index := make(map[*Entity]uint64, len(entities))
+	e.uvarint(uint64(len(entities)))
+	for i, entity := range entities {
+		index[entity] = uint64(i)
+
+		e.uint64(entity.ID)
+		e.rect(entity.Rect)
+		e.uvarint(uint64(len(actions[i])))
+		for _, a := range actions[i] {
+			e.uvarint(a)
+		}
+
+		e.uvarint(uint64(len(entity.Behaviours)))
+		for _, b := range entity.Behaviours {
+			e.uvarint(strs.index[b.Name])
+
+			// write parameters in key order so the output is stable
+			keys := make([]string, 0, len(b.Params))
+			for k := range b.Params {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			e.uvarint(uint64(len(keys)))
+			for _, k := range keys {
+				e.uvarint(strs.index[k])
+				e.uvarint(strs.index[b.Params[k]])
+			}
+		}
+	}
+
+	// node structure
+	if nodes {
+		e.node(q.node, index)
+	}
+
+	_, err := e.buf.WriteTo(w)
+	return err
+}
+
+// ReadBinary replaces the tree with the binary snapshot read from r.
+//
+// See UnmarshalBinary for the errors returned.
+func (q *Quadpix) ReadBinary(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return q.UnmarshalBinary(data)
+}
+
+// encoder writes the binary snapshot format.
+type encoder struct {
+	buf     bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uint16(v uint16) {
+	binary.LittleEndian.PutUint16(e.scratch[:2], v)
+	e.buf.Write(e.scratch[:2])
+}
+
+func (e *encoder) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(e.scratch[:8], v)
+	e.buf.Write(e.scratch[:8])
+}
+
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.scratch[:], v)
+	e.buf.Write(e.scratch[:n])
+}
+
+func (e *encoder) rect(r pixel.Rect) {
+	e.uint64(math.Float64bits(r.Min.X))
+	e.uint64(math.Float64bits(r.Min.Y))
+	e.uint64(math.Float64bits(r.Max.X))
+	e.uint64(math.Float64bits(r.Max.Y))
+}
+
+// node writes the given node and its children in pre-order.
+func (e *encoder) node(n *node, index map[*Entity]uint64) {
+	e.uvarint(uint64(len(n.entities)))
+	for _, entity := range n.entities {
+		e.uvarint(index[entity])
+	}
+
+	e.buf.WriteByte(byte(len(n.children)))
+	for i := range n.children {
+		e.node(n.children[i], index)
+	}
+}
+
+// decoder reads the binary snapshot format.
+//
+// The first error found is kept in err and all following reads return zero values.
+type decoder struct {
+	data []byte
+	off  int
+	err  error
+}
+
+// tree decodes a whole snapshot for the given tree.
+func (d *decoder) tree(q *Quadpix) (rect pixel.Rect, cfg config, entities Entities, root *node, err error) {
+	// header
+	if len(d.data) < len(binaryMagic) || string(d.data[:len(binaryMagic)]) != binaryMagic {
+		return rect, cfg, nil, nil, fmt.Errorf("%w: bad magic", ErrInvalidFormat)
+	}
+	d.off = len(binaryMagic)
+
+	version := d.uint16()
+	flags := d.uint16()
+	if d.err != nil {
+		return rect, cfg, nil, nil, d.err
+	}
+	if version == 0 || version > binaryVersion {
+		return rect, cfg, nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	if flags&^knownFlags != 0 {
+		return rect, cfg, nil, nil, fmt.Errorf("%w: unknown flags %#x", ErrUnsupportedVersion, flags&^knownFlags)
+	}
+
+	// config
+	rect = d.rect()
+	cfg.maxEntities = d.uvarint()
+	depth := d.uvarint()
+	if depth > math.MaxUint16 {
+		d.fail("max depth %d out of range", depth)
+	}
+	cfg.maxDepth = uint16(depth)
+	if version >= 3 {
+		cfg.looseness = math.Float64frombits(d.uint64())
+		if !validLooseness(cfg.looseness) {
+			d.fail("looseness %v out of range", cfg.looseness)
+		}
+	}
+	if version >= 4 {
+		cfg.overlap.Edges = EdgeMode(d.byte())
+		cfg.overlap.Epsilon = math.Float64frombits(d.uint64())
+		if !cfg.overlap.valid() {
+			d.fail("edges %v with epsilon %v out of range", cfg.overlap.Edges, cfg.overlap.Epsilon)
+		}
+	}
+
+	// string table
+	strs := make([]string, d.count(1))
+	for i := range strs {
+		strs[i] = d.string()
+	}
+
+	// entity table
+	entities = make(Entities, d.count(minEntitySize))
+	for i := range entities {
+		entity := &Entity{
+			ID:   d.uint64(),
+			Rect: d.rect(),
+		}
+
+		actions := d.count(1)
+		for j := 0; j < actions && d.err == nil; j++ {
+			name := d.str(strs)
+			if d.err != nil {
+				break
+			}
+
+			action, err := lookupActions([]string{name})
+			if err != nil {
+				return rect, cfg, nil, nil, err
+			}
+			entity.Actions = append(entity.Actions, action...)
+		}
+
+		if version >= 2 {
+			behaviours := d.count(2)
+			for j := 0; j < behaviours && d.err == nil; j++ {
+				b := Behaviour{
+					Name: d.str(strs),
+				}
+
+				params := d.count(2)
+				for k := 0; k < params && d.err == nil; k++ {
+					if b.Params == nil {
+						b.Params = make(map[string]string, params)
+					}
+					key := d.str(strs)
+					b.Params[key] = d.str(strs)
+				}
+
+				entity.Behaviours = append(entity.Behaviours, b)
+			}
+		}
+		if d.err != nil {
+			break
+		}
+
+		if err := entity.Bind(); err != nil {
+			return rect, cfg, nil, nil, err
+		}
+
+		entities[i] = entity
+	}
+	if d.err != nil {
+		return rect, cfg, nil, nil, d.err
+	}
+
+	// check the bounds before building any nodes
+	if !validRect(rect) || rect.W() == 0 || rect.H() == 0 {
+		return rect, cfg, nil, nil, fmt.Errorf("%w: root %v", ErrInvalidBounds, rect)
+	}
+	for _, e := range entities {
+		if !cfg.accepts(rect, e.Rect) {
+			return rect, cfg, nil, nil, fmt.Errorf("%w: entity %v", ErrInvalidBounds, e.ID)
+		}
+	}
+
+	// node structure
+	if flags&flagNodes != 0 {
+		// the config of q is not changed till the snapshot has been fully decoded
+		// so the nodes are built for a temporary tree with the decoded config.
+		tmp := &Quadpix{config: cfg}
+		root = tmp.newRoot(rect, minUint64(cfg.maxEntities, uint64(len(entities))))
+
+		seen := make([]bool, len(entities))
+		d.node(root, entities, seen)
+		if d.err != nil {
+			return rect, cfg, nil, nil, d.err
+		}
+
+		for i := range seen {
+			if !seen[i] {
+				return rect, cfg, nil, nil, fmt.Errorf("%w: entity %v not in any node", ErrInvalidFormat, entities[i].ID)
+			}
+		}
+		for _, e := range entities {
+			if !root.placed(e) {
+				return rect, cfg, nil, nil, fmt.Errorf("%w: entity %v missing from a leaf", ErrInvalidFormat, e.ID)
+			}
+		}
+
+		root.setTree(q)
+	}
+
+	if d.off != len(d.data) {
+		return rect, cfg, nil, nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidFormat, len(d.data)-d.off)
+	}
+
+	return rect, cfg, entities, root, nil
+}
+
+// node decodes the given node and its children.
+func (d *decoder) node(n *node, entities Entities, seen []bool) {
+	count := d.count(1)
+	for i := 0; i < count && d.err == nil; i++ {
+		index := d.uvarint()
+		if index >= uint64(len(entities)) {
+			d.fail("entity index %d out of range", index)
+			return
+		}
+
+		e := entities[index]
+		if !n.holds(e.Rect) {
+			d.fail("entity %v outside of its node", e.ID)
+			return
+		}
+		if n.entities.has(e) {
+			d.fail("entity %v stored twice in a node", e.ID)
+			return
+		}
+
+		if seen[index] && n.tree.looseness > 0 {
+			d.fail("entity %v stored in more then one node of a loose tree", e.ID)
+			return
+		}
+
+		n.entities = append(n.entities, e)
+		seen[index] = true
+	}
+
+	switch children := d.byte(); {
+	case d.err != nil:
+		return
+	case children == 0:
+		return
+	case children != 4:
+		d.fail("node with %d children", children)
+	case n.depth >= n.tree.maxDepth:
+		d.fail("node deeper then max depth %d", n.tree.maxDepth)
+	case len(n.entities) > 0 && n.tree.looseness == 0:
+		d.fail("branch node holding entities")
+	default:
+		n.split()
+		for i := range n.children {
+			d.node(n.children[i], entities, seen)
+		}
+	}
+}
+
+// fail records a format error if no error has been recorded yet.
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrInvalidFormat, fmt.Sprintf(format, args...))
+	}
+}
+
+// next returns the next n bytes of data.
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data)-d.off {
+		d.fail("%v", io.ErrUnexpectedEOF)
+		return nil
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.off:])
+	if n <= 0 {
+		d.fail("bad varint")
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+func (d *decoder) rect() pixel.Rect {
+	return pixel.R(
+		math.Float64frombits(d.uint64()),
+		math.Float64frombits(d.uint64()),
+		math.Float64frombits(d.uint64()),
+		math.Float64frombits(d.uint64()),
+	)
+}
+
+// count reads a count of items that each take at least size bytes.
+//
+// Counts that could not fit in the remaining data are rejected before anything is allocated for them.
+func (d *decoder) count(size int) int {
+	v := d.uvarint()
+	if d.err != nil {
+		return 0
+	}
+	if v > uint64((len(d.data)-d.off)/size) {
+		d.fail("count %d larger then remaining data", v)
+		return 0
+	}
+	return int(v)
+}
+
+func (d *decoder) string() string {
+	return string(d.next(d.count(1)))
+}
+
+// str reads an index in to the given string table.
+func (d *decoder) str(strs []string) string {
+	index := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if index >= uint64(len(strs)) {
+		d.fail("string index %d out of range", index)
+		return ""
+	}
+	return strs[index]
+}
+
+// stringTable holds each unique string written to a snapshot.
+type stringTable struct {
+	values []string
+	index  map[string]uint64
+}
+
+// add adds the given string to the table if needed and returns its index.
+func (t *stringTable) add(s string) uint64 {
+	i, ok := t.index[s]
+	if !ok {
+		i = uint64(len(t.values))
+		t.index[s] = i
+		t.values = append(t.values, s)
+	}
+	return i
+}
+
+// has checks if the given entity pointer is within the list of entities.
+func (e Entities) has(entity *Entity) bool {
+	for i := range e {
+		if e[i] == entity {
+			return true
+		}
+	}
+	return false
+}
+
+// placed checks that the given entity is stored in every leaf under this node that it intersects,
+// or for a loose tree in the node it belongs in.
+func (n *node) placed(e *Entity) bool {
+	if n.tree.looseness > 0 {
+		return n.home(e.Rect).entities.has(e)
+	}
+
+	if len(n.children) > 0 {
+		for _, child := range n.getQuadrant(e.Rect) {
+			if !child.placed(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return n.entities.has(e)
+}
+
+// setTree sets the tree of this node and all of its children.
+func (n *node) setTree(q *Quadpix) {
+	n.tree = q
+	for i := range n.children {
+		n.children[i].setTree(q)
+	}
+}
@@ -0,0 +1,267 @@
+package quadpix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+// binaryTestTree creates a tree with n random entities.
+func binaryTestTree(n int, seed int64) *Quadpix {
+	r := rand.New(rand.NewSource(seed))
+	tree := New(1000, 1000, 4, 6)
+
+	for i := 0; i < n; i++ {
+		x, y := r.Float64()*950, r.Float64()*950
+		w, h := r.Float64()*50, r.Float64()*50
+		tree.InsertEntities(&Entity{
+			ID:   uint64(i + 1),
+			Rect: pixel.R(x, y, x+w, y+h),
+		})
+	}
+
+	return tree
+}
+
+func TestQuadpix_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes bool
+	}{
+		{
+			name:  "with node structure",
+			nodes: true,
+		},
+		{
+			name:  "without node structure",
+			nodes: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := binaryTestTree(200, 1)
+			tree.Insert(pixel.R(0, 0, 10, 10), jsonTestAction)
+
+			var buf bytes.Buffer
+			if err := tree.WriteBinary(&buf, tt.nodes); err != nil {
+				t.Fatalf("Quadpix.WriteBinary() got error %v", err)
+			}
+
+			got := new(Quadpix)
+			if err := got.ReadBinary(&buf); err != nil {
+				t.Fatalf("Quadpix.ReadBinary() got error %v", err)
+			}
+
+			if got.rect != tree.rect || got.maxEntities != tree.maxEntities || got.maxDepth != tree.maxDepth {
+				t.Errorf("Quadpix.ReadBinary() config = %v %v %v, want %v %v %v",
+					got.rect, got.maxEntities, got.maxDepth, tree.rect, tree.maxEntities, tree.maxDepth)
+			}
+
+			want := tree.all()
+			if len(got.all()) != len(want) {
+				t.Errorf("Quadpix.ReadBinary() got %v entities, want %v", len(got.all()), len(want))
+			}
+			for _, e := range want {
+				if !<-got.IsEntity(e) {
+					t.Errorf("Quadpix.ReadBinary() entity not found %v", e)
+				}
+			}
+
+			// the loaded tree must still be usable
+			for _, e := range got.all() {
+				if err := got.Remove(e); err != nil {
+					t.Fatalf("Quadpix.Remove() on loaded tree got error %v", err)
+				}
+			}
+			if len(got.children) != 0 || len(got.entities) != 0 {
+				t.Errorf("Quadpix.Remove() on loaded tree did not empty the tree")
+			}
+		})
+	}
+}
+
+func TestQuadpix_UnmarshalBinaryErrors(t *testing.T) {
+	valid, err := binaryTestTree(10, 2).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Quadpix.MarshalBinary() got error %v", err)
+	}
+
+	withHeader := func(version, flags uint16) []byte {
+		data := append([]byte(nil), valid...)
+		binary.LittleEndian.PutUint16(data[4:], version)
+		binary.LittleEndian.PutUint16(data[6:], flags)
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "empty",
+			data:    nil,
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "bad magic",
+			data:    []byte("JSON{}"),
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "newer version",
+			data:    withHeader(binaryVersion+1, flagNodes),
+			wantErr: ErrUnsupportedVersion,
+		},
+		{
+			name:    "unknown flags",
+			data:    withHeader(binaryVersion, flagNodes|1<<7),
+			wantErr: ErrUnsupportedVersion,
+		},
+		{
+			name:    "truncated",
+			data:    valid[:len(valid)-3],
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "trailing data",
+			data:    append(append([]byte(nil), valid...), 0),
+			wantErr: ErrInvalidFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := New(800, 600, 10, 4)
+			if err := q.UnmarshalBinary(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Quadpix.UnmarshalBinary() got error %v, want %v", err, tt.wantErr)
+			}
+			if q.rect != pixel.R(0, 0, 800, 600) {
+				t.Errorf("Quadpix.UnmarshalBinary() changed tree on error")
+			}
+		})
+	}
+}
+
+func FuzzQuadpix_UnmarshalBinary(f *testing.F) {
+	for _, nodes := range []bool{true, false} {
+		var buf bytes.Buffer
+		binaryTestTree(20, 3).WriteBinary(&buf, nodes)
+		f.Add(buf.Bytes())
+	}
+	f.Add([]byte(binaryMagic))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		q := new(Quadpix)
+		if err := q.UnmarshalBinary(data); err != nil {
+			return
+		}
+
+		// anything that decodes must encode and decode to the same entities
+		out, err := q.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Quadpix.MarshalBinary() of decoded tree got error %v", err)
+		}
+
+		again := new(Quadpix)
+		if err := again.UnmarshalBinary(out); err != nil {
+			t.Fatalf("Quadpix.UnmarshalBinary() of encoded tree got error %v", err)
+		}
+		if len(again.all()) != len(q.all()) {
+			t.Fatalf("Quadpix.UnmarshalBinary() got %v entities, want %v", len(again.all()), len(q.all()))
+		}
+
+		// queries on the decoded tree must not panic
+		<-q.Retrieve(q.rect)
+	})
+}
+
+func BenchmarkQuadpix_MarshalBinary(b *testing.B) {
+	tree := binaryTestTree(100000, 4)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.MarshalBinary()
+	}
+}
+
+func BenchmarkQuadpix_UnmarshalBinary(b *testing.B) {
+	data, _ := binaryTestTree(100000, 4).MarshalBinary()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		new(Quadpix).UnmarshalBinary(data)
+	}
+}
+
+func BenchmarkQuadpix_UnmarshalJSON(b *testing.B) {
+	data, _ := json.Marshal(binaryTestTree(100000, 4))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		json.Unmarshal(data, new(Quadpix))
+	}
+}
+
+func TestQuadpix_LoadOutsideRoot(t *testing.T) {
+	// loose trees keep entities outside the root in the root node
+	tree := New(100, 100, 1, 4, Loose(2))
+	outside := &Entity{ID: 1, Rect: pixel.R(150, 150, 160, 160)}
+	if err := tree.InsertEntities(outside, &Entity{ID: 2, Rect: pixel.R(10, 10, 20, 20)}, &Entity{ID: 3, Rect: pixel.R(60, 60, 70, 70)}); err != nil {
+		t.Fatal(err)
+	}
+
+	load := map[string]func() (*Quadpix, error){
+		"json": func() (*Quadpix, error) {
+			data, err := json.Marshal(tree)
+			if err != nil {
+				return nil, err
+			}
+			got := new(Quadpix)
+			return got, json.Unmarshal(data, got)
+		},
+	}
+	for name, nodes := range map[string]bool{"binary with nodes": true, "binary": false} {
+		nodes := nodes
+		load[name] = func() (*Quadpix, error) {
+			var buf bytes.Buffer
+			if err := tree.WriteBinary(&buf, nodes); err != nil {
+				return nil, err
+			}
+			got := new(Quadpix)
+			return got, got.ReadBinary(&buf)
+		}
+	}
+
+	for name, fn := range load {
+		t.Run(name, func(t *testing.T) {
+			got, err := fn()
+			if err != nil {
+				t.Fatalf("loading the tree got error %v", err)
+			}
+
+			entities := <-got.Retrieve(pixel.R(140, 140, 170, 170))
+			if len(entities) != 1 || entities[0].ID != outside.ID || entities[0].Rect != outside.Rect {
+				t.Errorf("Retrieve() outside the root = %v, want %v", entities, outside)
+			}
+			if err := <-got.Validate(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// other trees can not hold entities outside the root
+	strict := New(100, 100, 1, 4)
+	strict.InsertEntities(outside)
+	var buf bytes.Buffer
+	if err := strict.WriteBinary(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := new(Quadpix).ReadBinary(&buf); !errors.Is(err, ErrInvalidBounds) {
+		t.Errorf("Quadpix.ReadBinary() got error %v, want %v", err, ErrInvalidBounds)
+	}
+}
@@ -0,0 +1,103 @@
+package quadpix
+
+import (
+	"sync"
+
+	"github.com/faiface/pixel"
+)
+
+// CommandBuffer records Insert, Remove and Update operations so they can be applied to the tree later with Flush.
+//
+// Changing the tree while iterating over entities returned from a query, or from inside an Action function,
+// can corrupt the leaf lists being iterated. A CommandBuffer lets you queue those changes and apply them
+// once it is safe to do so. Recording to a CommandBuffer is safe from multiple goroutines.
+type CommandBuffer struct {
+	tree *Quadpix
+
+	mu       sync.Mutex
+	commands []command
+}
+
+// NewCommandBuffer creates a new empty CommandBuffer for the tree.
+func (q *Quadpix) NewCommandBuffer() *CommandBuffer {
+	return &CommandBuffer{
+		tree: q,
+	}
+}
+
+// Insert records the insert of a new Entity with the given pixel.Rect bounds and Action functions.
+//
+// The created entity is returned so it can be referenced by later commands.
+func (b *CommandBuffer) Insert(rect pixel.Rect, action ...Action) *Entity {
+	entity := E(rect, action...)
+	b.push(command{op: OpInsert, entity: entity})
+	return entity
+}
+
+// InsertEntities records the insert of any number of Entity's.
+//
+// This function will return an error if no entities are given to InsertEntities.
+func (b *CommandBuffer) InsertEntities(entities ...*Entity) error {
+	// Check for no entities given.
+	if len(entities) == 0 {
+		return ErrNoEntitiesGiven
+	}
+
+	for _, e := range entities {
+		b.push(command{op: OpInsert, entity: e})
+	}
+
+	return nil
+}
+
+// Remove records the removal of the given entity.
+func (b *CommandBuffer) Remove(entity *Entity) {
+	b.push(command{op: OpRemove, entity: entity})
+}
+
+// Update records moving the given entity to the new pixel.Rect bounds.
+func (b *CommandBuffer) Update(entity *Entity, rect pixel.Rect) {
+	b.push(command{op: OpUpdate, entity: entity, rect: rect})
+}
+
+// Len returns the number of recorded commands waiting to be flushed.
+func (b *CommandBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.commands)
+}
+
+// Flush applies all recorded commands to the tree in the order they were recorded and clears the buffer.
+//
+// A failed command does not stop the flush. Flush returns a *CommandError for each command that failed,
+// or nil if every command succeeded. Commands recorded while a flush is running are kept for the next flush.
+//
+// Flush must not be called while a read operation on the tree is running.
+func (b *CommandBuffer) Flush() (errs []error) {
+	// take the recorded commands so recording can continue during the flush
+	b.mu.Lock()
+	commands := b.commands
+	b.commands = nil
+	b.mu.Unlock()
+
+	for i, c := range commands {
+		if err := b.tree.apply(c); err != nil {
+			errs = append(errs, &CommandError{
+				Index:  i,
+				Op:     c.op,
+				Entity: c.entity,
+				Err:    err,
+			})
+		}
+	}
+
+	return
+}
+
+// push adds the given command to the end of the buffer.
+func (b *CommandBuffer) push(c command) {
+	b.mu.Lock()
+	b.commands = append(b.commands, c)
+	b.mu.Unlock()
+}
@@ -0,0 +1,110 @@
+package quadpix
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestCommandBuffer_Flush(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+
+	entities := Entities{
+		&Entity{ID: 1, Rect: pixel.R(0, 0, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(20, 20, 40, 40)},
+		&Entity{ID: 3, Rect: pixel.R(500, 400, 550, 450)},
+	}
+	if err := tree.InsertEntities(entities...); err != nil {
+		t.Fatalf("CommandBuffer.Flush() got error on insert %v", err)
+	}
+
+	buf := tree.NewCommandBuffer()
+
+	// record changes while iterating over a query result
+	for _, e := range <-tree.Retrieve(pixel.R(0, 0, 800, 600)) {
+		if e.ID == 2 {
+			buf.Remove(e)
+		}
+	}
+	inserted := buf.Insert(pixel.R(100, 100, 120, 120))
+	buf.Update(entities[2], pixel.R(700, 500, 750, 550))
+	buf.Remove(E(pixel.R(300, 300, 310, 310)))
+
+	if buf.Len() != 4 {
+		t.Errorf("CommandBuffer.Len() = %v, want %v", buf.Len(), 4)
+	}
+
+	if <-tree.IsEntity(inserted) {
+		t.Errorf("CommandBuffer.Insert() entity in tree before Flush()")
+	}
+
+	errs := buf.Flush()
+	if len(errs) != 1 {
+		t.Fatalf("CommandBuffer.Flush() got errors %v, want 1", errs)
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(errs[0], &cmdErr) || cmdErr.Index != 3 || cmdErr.Op != OpRemove {
+		t.Errorf("CommandBuffer.Flush() got error %v, want remove error for command 3", errs[0])
+	}
+	if !errors.Is(errs[0], ErrNoEntityFound) {
+		t.Errorf("CommandBuffer.Flush() got error %v, want %v", errs[0], ErrNoEntityFound)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("CommandBuffer.Flush() left %v commands in buffer", buf.Len())
+	}
+
+	if <-tree.IsEntity(entities[1]) {
+		t.Errorf("CommandBuffer.Flush() did not remove %v", entities[1])
+	}
+	if !<-tree.IsEntity(inserted) {
+		t.Errorf("CommandBuffer.Flush() did not insert %v", inserted)
+	}
+	if !<-tree.IsEntity(entities[2]) || entities[2].Rect != pixel.R(700, 500, 750, 550) {
+		t.Errorf("CommandBuffer.Flush() did not update %v", entities[2])
+	}
+}
+
+func TestCommandBuffer_InsertEntities(t *testing.T) {
+	buf := New(800, 600, 10, 4).NewCommandBuffer()
+
+	if err := buf.InsertEntities(); err != ErrNoEntitiesGiven {
+		t.Errorf("CommandBuffer.InsertEntities() got error %v, want %v", err, ErrNoEntitiesGiven)
+	}
+
+	if err := buf.InsertEntities(E(pixel.R(0, 0, 10, 10)), E(pixel.R(10, 10, 20, 20))); err != nil {
+		t.Errorf("CommandBuffer.InsertEntities() got error %v", err)
+	}
+
+	if errs := buf.Flush(); errs != nil {
+		t.Errorf("CommandBuffer.Flush() got errors %v", errs)
+	}
+}
+
+func TestCommandBuffer_FlushOutOfBounds(t *testing.T) {
+	tree := New(800, 600, 1, 4)
+	a := E(pixel.R(10, 10, 20, 20))
+	b := E(pixel.R(500, 400, 510, 410))
+	if err := tree.InsertEntities(a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// bounds outside the root of a split tree can not be stored in any node
+	buf := tree.NewCommandBuffer()
+	buf.Update(a, pixel.R(900, 900, 910, 910))
+	outside := buf.Insert(pixel.R(-100, -100, -90, -90))
+
+	errs := buf.Flush()
+	if len(errs) != 2 || !errors.Is(errs[0], ErrInvalidBounds) || !errors.Is(errs[1], ErrInvalidBounds) {
+		t.Fatalf("CommandBuffer.Flush() got errors %v, want 2 wrapping %v", errs, ErrInvalidBounds)
+	}
+
+	if !<-tree.IsEntity(a) || a.Rect != pixel.R(10, 10, 20, 20) {
+		t.Errorf("CommandBuffer.Flush() lost %v after a failed update", a)
+	}
+	if tree.all().Contains(outside) {
+		t.Errorf("CommandBuffer.Flush() inserted %v", outside)
+	}
+}
@@ -0,0 +1,485 @@
+package quadpix
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// BVH is a dynamic bounding volume hierarchy, a binary tree of axis aligned bounding boxes with one entity in each leaf.
+//
+// Each leaf stores its entity under fat bounds, the entity's bounds grown by a margin on every side. An Update that keeps
+// the entity with in its fat bounds does not change the tree at all, so entities moving a little each frame never cause
+// the split and collapse churn a quadtree has. Entities that leave there fat bounds are moved to a new leaf and the tree
+// is kept balanced by rotating the nodes above it.
+//
+// A BVH has no bounds and works best for scenes where most entities move a short distance every frame.
+// It implements SpatialIndex and follows the same rules as Quadpix.
+type BVH struct {
+	margin  float64
+	overlap Overlap
+
+	root *bvhNode
+
+	// leaves holds the leaf of every entity by the entity's ID.
+	leaves map[uint64][]*bvhNode
+	count  int
+}
+
+// bvhNode is a node of a BVH. Leafs hold an entity and branches always have two children.
+type bvhNode struct {
+	fat    pixel.Rect
+	height int
+
+	parent      *bvhNode
+	left, right *bvhNode
+
+	entity *Entity
+}
+
+func (n *bvhNode) leaf() bool {
+	return n.left == nil
+}
+
+var _ SpatialIndex = (*BVH)(nil)
+
+// NewBVH creates a new empty BVH which grows the bounds of every leaf by the given margin.
+//
+// A margin of about how far entities move in a few frames is a good start. The Edges and Epsilon Options set how
+// the BVH's queries treat touching entities and all other Options are ignored.
+// NewBVH panics if the margin is negative or not finite.
+func NewBVH(margin float64, opts ...Option) *BVH {
+	if !(margin >= 0) || math.IsInf(margin, 0) {
+		panic(fmt.Sprintf("quadpix: invalid bvh margin %v", margin))
+	}
+
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &BVH{
+		margin:  margin,
+		overlap: cfg.overlap,
+		leaves:  make(map[uint64][]*bvhNode),
+	}
+}
+
+// Margin returns the distance the bounds of every leaf are grown by.
+func (b *BVH) Margin() float64 {
+	return b.margin
+}
+
+// Len returns the number of entities in the BVH.
+func (b *BVH) Len() int {
+	return b.count
+}
+
+// Height returns the number of nodes on the longest path from the root to a leaf.
+func (b *BVH) Height() int {
+	if b.root == nil {
+		return 0
+	}
+	return b.root.height + 1
+}
+
+// Insert adds the given pixel.Rect to the BVH as an entity bound with the given Actions.
+func (b *BVH) Insert(rect pixel.Rect, action ...Action) {
+	b.insert(E(rect, action...))
+}
+
+// InsertEntities inserts any number of Entity's in to the BVH.
+//
+// Like Quadpix, entities that have Behaviours but no Actions get their Actions bound from their Behaviours first.
+// InsertEntities returns ErrNoEntitiesGiven if no entities are given, and on error no entities are inserted.
+func (b *BVH) InsertEntities(entities ...*Entity) error {
+	if len(entities) == 0 {
+		return ErrNoEntitiesGiven
+	}
+
+	if err := bindEntities(entities); err != nil {
+		return err
+	}
+
+	for _, e := range entities {
+		b.insert(e)
+	}
+
+	return nil
+}
+
+// Remove the given entity from the BVH.
+//
+// Remove returns ErrNoEntityFound if no entity with the same ID and pixel.Rect is in the BVH.
+func (b *BVH) Remove(entity *Entity) error {
+	leaf := b.find(entity)
+	if leaf == nil {
+		return ErrNoEntityFound
+	}
+
+	b.removeLeaf(leaf)
+	b.forget(leaf)
+	b.count--
+
+	return nil
+}
+
+// Update moves the given entity to the new pixel.Rect bounds within the BVH.
+//
+// If the new bounds are still with in the fat bounds of the entity's leaf the tree is not changed.
+// Update returns ErrNoEntityFound if the entity is not in the BVH. On success the given entity's Rect is set to the new bounds.
+func (b *BVH) Update(entity *Entity, rect pixel.Rect) error {
+	leaf := b.find(entity)
+	if leaf == nil {
+		return ErrNoEntityFound
+	}
+
+	entity.Rect = rect
+	leaf.entity = entity
+
+	if encloses(leaf.fat, rect) {
+		return nil
+	}
+
+	// the entity left its fat bounds so it is moved to a new place in the tree
+	b.removeLeaf(leaf)
+	leaf.fat = b.fatten(rect)
+	leaf.parent = nil
+	b.insertLeaf(leaf)
+
+	return nil
+}
+
+// Retrieve gets all entities whose fat bounds the given pixel.Rect reaches.
+//
+// Retrieve returns a channel of entities as all Read-Only operations are run on there own thread.
+func (b *BVH) Retrieve(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		var entities Entities
+		b.query(rect, func(e *Entity) bool {
+			entities = append(entities, e)
+			return true
+		})
+
+		out <- entities
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersect returns whether or not the given pixel.Rect intersects any entity with in the BVH.
+//
+// Intersect returns a channel of a bool as all Read-Only operations are run on there own thread.
+func (b *BVH) Intersect(rect pixel.Rect) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		hit := false
+		b.query(rect, func(e *Entity) bool {
+			hit = b.overlap.Intersects(e.Rect, rect)
+			return !hit
+		})
+
+		out <- hit
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersects returns a channel of all entities that intersect with the given pixel.Rect within the BVH.
+//
+// Intersects returns a channel of Entities as all Read-Only operations are run on there own thread.
+func (b *BVH) Intersects(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		var entities Entities
+		b.query(rect, func(e *Entity) bool {
+			if b.overlap.Intersects(e.Rect, rect) {
+				entities = append(entities, e)
+			}
+			return true
+		})
+
+		out <- entities
+		close(out)
+	}()
+
+	return out
+}
+
+// IsEntity returns whether or not the given entity exists with in the BVH.
+//
+// IsEntity returns a channel holding a bool as all Read-Only operations are run on there own thread.
+// The given entity must have the same ID and pixel.Rect bounds to be found.
+func (b *BVH) IsEntity(entity *Entity) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		out <- b.find(entity) != nil
+		close(out)
+	}()
+
+	return out
+}
+
+// fatten returns the given pixel.Rect grown by the margin on every side.
+func (b *BVH) fatten(rect pixel.Rect) pixel.Rect {
+	m := b.margin
+	return pixel.R(rect.Min.X-m, rect.Min.Y-m, rect.Max.X+m, rect.Max.Y+m)
+}
+
+// find returns the leaf holding an entity equal to the given entity, or nil if there is none.
+func (b *BVH) find(entity *Entity) *bvhNode {
+	for _, leaf := range b.leaves[entity.ID] {
+		if leaf.entity.IsEqual(entity) {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// forget drops the given leaf from the leaves of its entity's ID.
+func (b *BVH) forget(leaf *bvhNode) {
+	id := leaf.entity.ID
+	leaves := b.leaves[id]
+	for i := range leaves {
+		if leaves[i] == leaf {
+			leaves = append(leaves[:i], leaves[i+1:]...)
+			break
+		}
+	}
+
+	if len(leaves) == 0 {
+		delete(b.leaves, id)
+	} else {
+		b.leaves[id] = leaves
+	}
+}
+
+// query calls fn with the entity of every leaf whose fat bounds the given pixel.Rect reaches until fn returns false.
+func (b *BVH) query(rect pixel.Rect, fn func(e *Entity) bool) {
+	if b.root == nil {
+		return
+	}
+
+	// fat bounds are matched the same way a tree matches nodes so touching entities are never missed
+	o := b.overlap.placement()
+
+	stack := []*bvhNode{b.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if !o.Intersects(n.fat, rect) {
+			continue
+		}
+
+		if n.leaf() {
+			if !fn(n.entity) {
+				return
+			}
+			continue
+		}
+
+		stack = append(stack, n.left, n.right)
+	}
+}
+
+// insert adds a new leaf for the given entity.
+func (b *BVH) insert(entity *Entity) {
+	leaf := &bvhNode{
+		fat:    b.fatten(entity.Rect),
+		entity: entity,
+	}
+
+	b.leaves[entity.ID] = append(b.leaves[entity.ID], leaf)
+	b.count++
+
+	b.insertLeaf(leaf)
+}
+
+// insertLeaf places the given leaf next to the sibling that grows the tree the least.
+func (b *BVH) insertLeaf(leaf *bvhNode) {
+	if b.root == nil {
+		b.root = leaf
+		return
+	}
+
+	// walk down to the cheapest sibling, where the cost is the perimeter added to every node on the way
+	n := b.root
+	for !n.leaf() {
+		perimeter := bvhPerimeter(n.fat)
+		combined := bvhPerimeter(n.fat.Union(leaf.fat))
+
+		// cost of making a new parent for the leaf and this node
+		cost := 2 * combined
+		// cost of pushing the leaf further down, which grows this node
+		inheritance := 2 * (combined - perimeter)
+
+		left := inheritance + b.descendCost(n.left, leaf)
+		right := inheritance + b.descendCost(n.right, leaf)
+
+		if cost < left && cost < right {
+			break
+		}
+
+		if left < right {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	// make a new parent for the sibling and the leaf
+	sibling := n
+	parent := &bvhNode{
+		fat:    sibling.fat.Union(leaf.fat),
+		height: sibling.height + 1,
+		parent: sibling.parent,
+		left:   sibling,
+		right:  leaf,
+	}
+
+	if old := sibling.parent; old != nil {
+		if old.left == sibling {
+			old.left = parent
+		} else {
+			old.right = parent
+		}
+	} else {
+		b.root = parent
+	}
+	sibling.parent = parent
+	leaf.parent = parent
+
+	b.refit(parent.parent)
+}
+
+// descendCost returns the cost of placing the given leaf under the given child.
+func (b *BVH) descendCost(child, leaf *bvhNode) float64 {
+	combined := bvhPerimeter(child.fat.Union(leaf.fat))
+	if child.leaf() {
+		return combined
+	}
+	return combined - bvhPerimeter(child.fat)
+}
+
+// removeLeaf takes the given leaf out of the tree, replacing its parent with its sibling.
+func (b *BVH) removeLeaf(leaf *bvhNode) {
+	if leaf == b.root {
+		b.root = nil
+		return
+	}
+
+	parent := leaf.parent
+	sibling := parent.left
+	if sibling == leaf {
+		sibling = parent.right
+	}
+
+	grand := parent.parent
+	sibling.parent = grand
+	if grand == nil {
+		b.root = sibling
+		return
+	}
+
+	if grand.left == parent {
+		grand.left = sibling
+	} else {
+		grand.right = sibling
+	}
+
+	b.refit(grand)
+}
+
+// refit walks up from the given node balancing each node and fixing its bounds and height.
+func (b *BVH) refit(n *bvhNode) {
+	for n != nil {
+		n = b.balance(n)
+
+		n.height = 1 + maxInt(n.left.height, n.right.height)
+		n.fat = n.left.fat.Union(n.right.fat)
+
+		n = n.parent
+	}
+}
+
+// balance rotates the taller child of the given node up if the heights of its children differ by more then one.
+//
+// It returns the node now in the place of the given node.
+func (b *BVH) balance(a *bvhNode) *bvhNode {
+	if a.leaf() || a.height < 2 {
+		return a
+	}
+
+	diff := a.right.height - a.left.height
+	switch {
+	case diff > 1:
+		return b.rotate(a, a.right, a.left, true)
+	case diff < -1:
+		return b.rotate(a, a.left, a.right, false)
+	default:
+		return a
+	}
+}
+
+// rotate moves the child c of a up in to a's place, with the other child of a staying under a.
+//
+// The taller child of c stays under c and the shorter one is moved under a. right is true if c is a's right child.
+func (b *BVH) rotate(a, c, other *bvhNode, right bool) *bvhNode {
+	f, g := c.left, c.right
+
+	// c takes the place of a
+	c.parent = a.parent
+	if c.parent != nil {
+		if c.parent.left == a {
+			c.parent.left = c
+		} else {
+			c.parent.right = c
+		}
+	} else {
+		b.root = c
+	}
+	a.parent = c
+
+	// keep the taller grandchild under c
+	keep, move := f, g
+	if g.height > f.height {
+		keep, move = g, f
+	}
+
+	if right {
+		c.left, c.right = a, keep
+		a.right = move
+	} else {
+		c.left, c.right = keep, a
+		a.left = move
+	}
+	move.parent = a
+
+	a.fat = other.fat.Union(move.fat)
+	a.height = 1 + maxInt(other.height, move.height)
+	c.fat = a.fat.Union(keep.fat)
+	c.height = 1 + maxInt(a.height, keep.height)
+
+	return c
+}
+
+// bvhPerimeter returns the perimeter of the given pixel.Rect, the cost of a node in a BVH.
+func bvhPerimeter(r pixel.Rect) float64 {
+	return 2 * (r.W() + r.H())
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
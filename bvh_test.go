@@ -0,0 +1,176 @@
+package quadpix
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+// validate checks the links, bounds and heights of every node in the BVH.
+func (b *BVH) validate() error {
+	if b.root == nil {
+		if b.count != 0 {
+			return fmt.Errorf("empty root with %v entities", b.count)
+		}
+		return nil
+	}
+	if b.root.parent != nil {
+		return fmt.Errorf("root has a parent")
+	}
+
+	leaves := 0
+	var walk func(n *bvhNode) error
+	walk = func(n *bvhNode) error {
+		if n.leaf() {
+			leaves++
+			if n.right != nil || n.height != 0 {
+				return fmt.Errorf("leaf %v has a child or height %v", n.entity, n.height)
+			}
+			if !encloses(n.fat, n.entity.Rect) {
+				return fmt.Errorf("leaf %v fat bounds %v do not enclose the entity", n.entity, n.fat)
+			}
+			if b.find(n.entity) != n {
+				return fmt.Errorf("leaf %v can not be found by ID", n.entity)
+			}
+			return nil
+		}
+
+		if n.right == nil || n.left.parent != n || n.right.parent != n {
+			return fmt.Errorf("branch %v has broken child links", n.fat)
+		}
+		if n.fat != n.left.fat.Union(n.right.fat) {
+			return fmt.Errorf("branch bounds %v are not the union of its children", n.fat)
+		}
+		if n.height != 1+maxInt(n.left.height, n.right.height) {
+			return fmt.Errorf("branch %v has height %v", n.fat, n.height)
+		}
+
+		if err := walk(n.left); err != nil {
+			return err
+		}
+		return walk(n.right)
+	}
+
+	if err := walk(b.root); err != nil {
+		return err
+	}
+	if leaves != b.count {
+		return fmt.Errorf("%v leaves for %v entities", leaves, b.count)
+	}
+	return nil
+}
+
+// shape returns the bounds of every node of the BVH in order.
+func (b *BVH) shape() (rects []pixel.Rect) {
+	var walk func(n *bvhNode)
+	walk = func(n *bvhNode) {
+		if n == nil {
+			return
+		}
+		rects = append(rects, n.fat)
+		walk(n.left)
+		walk(n.right)
+	}
+	walk(b.root)
+	return
+}
+
+func TestBVH_balance(t *testing.T) {
+	tests := []struct {
+		name string
+		rect func(i int, r *rand.Rand) pixel.Rect
+	}{
+		{"random", func(i int, r *rand.Rand) pixel.Rect { return randomRect(r, 20) }},
+		// entities inserted in order are the worst case for a tree without rotations
+		{"sorted", func(i int, r *rand.Rand) pixel.Rect {
+			x := float64(i) * 2
+			return pixel.R(x, 0, x+1, 1)
+		}},
+		{"stacked", func(i int, r *rand.Rand) pixel.Rect { return pixel.R(10, 10, 20, 20) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			b := NewBVH(2)
+
+			entities := make(Entities, 2000)
+			for i := range entities {
+				entities[i] = &Entity{ID: uint64(i + 1), Rect: tt.rect(i, r)}
+				b.InsertEntities(entities[i])
+			}
+			if err := b.validate(); err != nil {
+				t.Fatalf("BVH.validate() after inserts got error %v", err)
+			}
+
+			// the rotations keep the tree close to the height of a balanced tree, which is less then 1.45 log2(n) for n leafs
+			if max := int(1.45*math.Log2(float64(len(entities)))) + 2; b.Height() > max {
+				t.Errorf("BVH.Height() = %v, want at most %v", b.Height(), max)
+			}
+
+			for _, e := range entities[:1500] {
+				if err := b.Remove(e); err != nil {
+					t.Fatalf("BVH.Remove() got error %v", err)
+				}
+			}
+			if err := b.validate(); err != nil {
+				t.Fatalf("BVH.validate() after removes got error %v", err)
+			}
+		})
+	}
+}
+
+func TestBVH_Update(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	b := NewBVH(4)
+
+	entities := make(Entities, 500)
+	for i := range entities {
+		entities[i] = &Entity{ID: uint64(i + 1), Rect: randomRect(r, 20)}
+	}
+	b.InsertEntities(entities...)
+
+	// moves with in the margin do not change the tree
+	before := fmt.Sprint(b.shape())
+	for step := 0; step < 4; step++ {
+		for _, e := range entities {
+			if err := b.Update(e, e.Rect.Moved(pixel.V(1, -1))); err != nil {
+				t.Fatalf("BVH.Update() got error %v", err)
+			}
+		}
+	}
+	if after := fmt.Sprint(b.shape()); after != before {
+		t.Errorf("BVH.Update() with in the margin changed the tree")
+	}
+
+	// moves past the margin move the leaf and keep the tree valid
+	for _, e := range entities {
+		if err := b.Update(e, e.Rect.Moved(pixel.V(r.Float64()*200-100, r.Float64()*200-100))); err != nil {
+			t.Fatalf("BVH.Update() got error %v", err)
+		}
+	}
+	if err := b.validate(); err != nil {
+		t.Fatalf("BVH.validate() got error %v", err)
+	}
+	for _, e := range entities {
+		if got := <-b.Intersects(e.Rect); !got.Contains(e) {
+			t.Fatalf("BVH.Intersects(%v) = %v, missing the moved entity", e.Rect, got)
+		}
+	}
+}
+
+func TestNewBVH_panics(t *testing.T) {
+	for _, margin := range []float64{-1, math.Inf(1), math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewBVH(%v) did not panic", margin)
+				}
+			}()
+			NewBVH(margin)
+		}()
+	}
+}
@@ -0,0 +1,379 @@
+// Command quadpix inspects saved Quadpix trees.
+//
+// Trees can be saved with json.Marshal or Quadpix.WriteBinary. Usage:
+//
+//	quadpix stats <file>
+//	quadpix validate <file>
+//	quadpix query <file> <minX> <minY> <maxX> <maxY>
+//	quadpix point <file> <x> <y>
+//	quadpix ascii [-cols n] [-rows n] <file>
+//	quadpix render [-scale n] [-mode depth|load] [-query minX,minY,maxX,maxY] <file> <out.png|out.svg>
+//	quadpix replay <log>
+//
+// Actions saved with the tree do not need to be registered, they are loaded as empty placeholders.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Tskken/quadpix"
+	"github.com/faiface/pixel"
+)
+
+// errUsage is returned for bad command line arguments.
+var errUsage = errors.New("usage: quadpix <stats|validate|query|point|ascii|render|replay> [flags] <file> [args]")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments and returns its exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if err := command(args, stdout); err != nil {
+		fmt.Fprintln(stderr, "quadpix:", err)
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// command runs the sub command named by the first argument.
+func command(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "stats":
+		return stats(args[1:], stdout)
+	case "validate":
+		return validate(args[1:], stdout)
+	case "query":
+		return query(args[1:], stdout)
+	case "point":
+		return point(args[1:], stdout)
+	case "ascii":
+		return ascii(args[1:], stdout)
+	case "render":
+		return render(args[1:], stdout)
+	case "replay":
+		return replay(args[1:], stdout)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+	}
+}
+
+// load reads a tree saved as JSON or as a binary snapshot.
+func load(path string) (*quadpix.Quadpix, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the inspector does not have the game's actions, so every action is loaded as a placeholder
+	previous := quadpix.SetActionFallback(func(quadpix.Behaviour) (quadpix.Action, error) {
+		return func() {}, nil
+	})
+	defer quadpix.SetActionFallback(previous)
+
+	tree := new(quadpix.Quadpix)
+	if bytes.HasPrefix(data, []byte("QPIX")) {
+		err = tree.UnmarshalBinary(data)
+	} else {
+		err = json.Unmarshal(data, tree)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return tree, nil
+}
+
+// stats prints the stats of a tree.
+func stats(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	tree, err := load(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(stdout, <-tree.Stats())
+	return nil
+}
+
+// validate checks the structure of a tree.
+func validate(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	tree, err := load(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := <-tree.Validate(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "ok")
+	return nil
+}
+
+// replay runs a log written by a quadpix.Recorder and prints the stats of the replayed tree.
+func replay(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tree, err := quadpix.Replay(f)
+	if err != nil {
+		return fmt.Errorf("%v: %w", args[0], err)
+	}
+
+	fmt.Fprint(stdout, <-tree.Stats())
+	return nil
+}
+
+// query prints all entities intersecting a rect.
+func query(args []string, stdout io.Writer) error {
+	if len(args) != 5 {
+		return errUsage
+	}
+
+	tree, err := load(args[0])
+	if err != nil {
+		return err
+	}
+
+	v, err := floats(args[1:])
+	if err != nil {
+		return err
+	}
+
+	rect := pixel.R(v[0], v[1], v[2], v[3])
+	if err := inBounds(tree, rect); err != nil {
+		return err
+	}
+
+	printEntities(stdout, <-tree.Intersects(rect))
+	return nil
+}
+
+// point prints all entities intersecting a point.
+func point(args []string, stdout io.Writer) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+
+	tree, err := load(args[0])
+	if err != nil {
+		return err
+	}
+
+	v, err := floats(args[1:])
+	if err != nil {
+		return err
+	}
+
+	rect := pixel.R(v[0], v[1], v[0], v[1])
+	if err := inBounds(tree, rect); err != nil {
+		return err
+	}
+
+	printEntities(stdout, <-tree.Intersects(rect))
+	return nil
+}
+
+// ascii prints a map of how many entities each leaf holds.
+//
+// Each character is the leaf at the centre of that cell: '.' for an empty leaf,
+// 1 to 9 for the number of entities and '#' for 10 or more.
+func ascii(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ascii", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	cols := flags.Int("cols", 64, "number of columns")
+	rows := flags.Int("rows", 32, "number of rows")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *cols <= 0 || *rows <= 0 {
+		return errUsage
+	}
+
+	tree, err := load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var leaves []quadpix.NodeInfo
+	for _, n := range <-tree.Nodes() {
+		if n.Leaf {
+			leaves = append(leaves, n)
+		}
+	}
+
+	bounds := (<-tree.Stats()).Bounds
+	w, h := bounds.W()/float64(*cols), bounds.H()/float64(*rows)
+
+	var b strings.Builder
+	for row := 0; row < *rows; row++ {
+		for col := 0; col < *cols; col++ {
+			// rows are printed top down
+			c := pixel.V(
+				bounds.Min.X+(float64(col)+0.5)*w,
+				bounds.Max.Y-(float64(row)+0.5)*h,
+			)
+			b.WriteByte(occupancy(leaves, c))
+		}
+		b.WriteByte('\n')
+	}
+
+	fmt.Fprint(stdout, b.String())
+	return nil
+}
+
+// occupancy returns the map character for the leaf holding the given point.
+func occupancy(leaves []quadpix.NodeInfo, v pixel.Vec) byte {
+	for _, leaf := range leaves {
+		if !leaf.Bounds.Contains(v) {
+			continue
+		}
+
+		switch {
+		case leaf.Entities == 0:
+			return '.'
+		case leaf.Entities < 10:
+			return byte('0' + leaf.Entities)
+		default:
+			return '#'
+		}
+	}
+	return ' '
+}
+
+// render writes a picture of a tree to a PNG or SVG file.
+func render(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	scale := flags.Float64("scale", 1, "image pixels per unit of the tree's bounds")
+	mode := flags.String("mode", "depth", "colour nodes by depth or load")
+	highlight := flags.String("query", "", "query bounds to highlight as minX,minY,maxX,maxY")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+	if math.IsNaN(*scale) || math.IsInf(*scale, 0) {
+		return fmt.Errorf("%w: bad scale %v", errUsage, *scale)
+	}
+
+	opts := quadpix.ExportOptions{
+		Scale: *scale,
+	}
+
+	switch *mode {
+	case "depth":
+		opts.Mode = quadpix.ColorByDepth
+	case "load":
+		opts.Mode = quadpix.ColorByLoad
+	default:
+		return fmt.Errorf("%w: unknown mode %q", errUsage, *mode)
+	}
+
+	if *highlight != "" {
+		v, err := floats(strings.Split(*highlight, ","))
+		if err != nil || len(v) != 4 {
+			return fmt.Errorf("%w: bad query %q", errUsage, *highlight)
+		}
+		opts.Highlights = []pixel.Rect{pixel.R(v[0], v[1], v[2], v[3])}
+	}
+
+	tree, err := load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, rect := range opts.Highlights {
+		if err := inBounds(tree, rect); err != nil {
+			return err
+		}
+	}
+
+	out := flags.Arg(1)
+	ext := strings.ToLower(filepath.Ext(out))
+	if ext != ".svg" && ext != ".png" {
+		return fmt.Errorf("%w: output must be a .png or .svg file", errUsage)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	if ext == ".svg" {
+		err = tree.WriteSVG(f, opts)
+	} else {
+		err = tree.WritePNG(f, opts)
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// do not leave a partly written picture behind
+		os.Remove(out)
+		return err
+	}
+
+	fmt.Fprintln(stdout, "wrote", out)
+	return nil
+}
+
+// inBounds checks that the given query bounds are valid and reach the tree, as queries outside
+// the bounds of the tree find no nodes to search.
+func inBounds(tree *quadpix.Quadpix, rect pixel.Rect) error {
+	bounds := (<-tree.Stats()).Bounds
+	if !(rect.Min.X <= rect.Max.X && rect.Min.Y <= rect.Max.Y) || !bounds.Intersects(rect) {
+		return fmt.Errorf("query %v is outside the tree bounds %v", rect, bounds)
+	}
+	return nil
+}
+
+// floats parses each argument as a float64.
+func floats(args []string) ([]float64, error) {
+	v := make([]float64, len(args))
+	for i, arg := range args {
+		f, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		v[i] = f
+	}
+	return v, nil
+}
+
+// printEntities prints one line for each entity.
+func printEntities(w io.Writer, entities quadpix.Entities) {
+	for _, e := range entities {
+		fmt.Fprintf(w, "%d\t%v\n", e.ID, e.Rect)
+	}
+	fmt.Fprintf(w, "%d entities\n", len(entities))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Tskken/quadpix"
+	"github.com/faiface/pixel"
+)
+
+// writeTree saves a small tree in the given format and returns its path.
+func writeTree(t *testing.T, dir string, binary bool) string {
+	tree := quadpix.New(800, 600, 2, 4)
+
+	// entity 3 has a behaviour the inspector does not know about
+	behaviours := []quadpix.Behaviour{quadpix.B("not-registered", "a", "b")}
+
+	tree.InsertEntities(
+		&quadpix.Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&quadpix.Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+		&quadpix.Entity{ID: 3, Rect: pixel.R(350, 250, 450, 350), Behaviours: behaviours, Actions: []quadpix.Action{func() {}}},
+	)
+
+	var data []byte
+	var err error
+	path := filepath.Join(dir, "tree.json")
+	if binary {
+		data, err = tree.MarshalBinary()
+		path = filepath.Join(dir, "tree.qpix")
+	} else {
+		data, err = json.Marshal(tree)
+	}
+	if err != nil {
+		t.Fatalf("could not save tree: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("could not write tree: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quadpix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, binary := range []bool{false, true} {
+		path := writeTree(t, dir, binary)
+
+		tests := []struct {
+			name     string
+			args     []string
+			wantCode int
+			wantOut  string
+		}{
+			{
+				name:     "stats",
+				args:     []string{"stats", path},
+				wantCode: 0,
+				wantOut:  "entities: 3\n",
+			},
+			{
+				name:     "validate",
+				args:     []string{"validate", path},
+				wantCode: 0,
+				wantOut:  "ok\n",
+			},
+			{
+				name:     "query",
+				args:     []string{"query", path, "0", "0", "100", "100"},
+				wantCode: 0,
+				wantOut:  "1\tRect(10, 10, 50, 50)\n1 entities\n",
+			},
+			{
+				name:     "point",
+				args:     []string{"point", path, "400", "300"},
+				wantCode: 0,
+				wantOut:  "3\tRect(350, 250, 450, 350)\n1 entities\n",
+			},
+			{
+				name:     "ascii",
+				args:     []string{"ascii", "-cols", "4", "-rows", "2", path},
+				wantCode: 0,
+				wantOut:  "1122\n2211\n",
+			},
+			{
+				name:     "render",
+				args:     []string{"render", "-mode", "load", "-query", "0,0,100,100", path, filepath.Join(dir, "tree.svg")},
+				wantCode: 0,
+				wantOut:  "wrote",
+			},
+			{
+				name:     "query outside",
+				args:     []string{"query", path, "5000", "5000", "6000", "6000"},
+				wantCode: 1,
+			},
+			{
+				name:     "point outside",
+				args:     []string{"point", path, "-10", "300"},
+				wantCode: 1,
+			},
+			{
+				name:     "nan point",
+				args:     []string{"point", path, "NaN", "300"},
+				wantCode: 1,
+			},
+			{
+				name:     "render query outside",
+				args:     []string{"render", "-query", "5000,5000,6000,6000", path, filepath.Join(dir, "outside.svg")},
+				wantCode: 1,
+			},
+			{
+				name:     "render huge scale",
+				args:     []string{"render", "-scale", "1e6", path, filepath.Join(dir, "huge.png")},
+				wantCode: 1,
+			},
+			{
+				name:     "render nan scale",
+				args:     []string{"render", "-scale", "NaN", path, filepath.Join(dir, "nan.png")},
+				wantCode: 2,
+			},
+			{
+				name:     "unknown command",
+				args:     []string{"explode", path},
+				wantCode: 2,
+			},
+			{
+				name:     "missing file",
+				args:     []string{"stats", filepath.Join(dir, "missing.json")},
+				wantCode: 1,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var stdout, stderr bytes.Buffer
+				if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+					t.Fatalf("run() = %v, want %v, stderr: %s", code, tt.wantCode, stderr.String())
+				}
+				if !strings.Contains(stdout.String(), tt.wantOut) {
+					t.Errorf("run() output = %q, want %q", stdout.String(), tt.wantOut)
+				}
+
+				// the placeholder actions used while loading must not be left in the registry
+				if _, err := quadpix.B("main-test-missing").Bind(); !errors.Is(err, quadpix.ErrUnknownAction) {
+					t.Errorf("run() left an action fallback set, Bind() got error %v", err)
+				}
+			})
+		}
+
+		// a picture that could not be drawn is not left behind
+		if _, err := os.Stat(filepath.Join(dir, "huge.png")); !os.IsNotExist(err) {
+			t.Errorf("render left a file for a failed picture, stat got error %v", err)
+		}
+	}
+}
+
+func TestRun_replay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quadpix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tree := quadpix.New(800, 600, 2, 4)
+	var log bytes.Buffer
+	if _, err := tree.StartRecording(&log); err != nil {
+		t.Fatal(err)
+	}
+	tree.Insert(pixel.R(10, 10, 50, 50))
+	<-tree.Retrieve(pixel.R(0, 0, 100, 100))
+
+	good := filepath.Join(dir, "good.log")
+	bad := filepath.Join(dir, "bad.log")
+	ioutil.WriteFile(good, log.Bytes(), 0644)
+	ioutil.WriteFile(bad, bytes.Replace(log.Bytes(), []byte(`"result":[1]`), []byte(`"result":[2]`), 1), 0644)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"matching log", []string{"replay", good}, 0, "entities:"},
+		{"changed log", []string{"replay", bad}, 1, ""},
+		{"missing log", []string{"replay", filepath.Join(dir, "missing.log")}, 1, ""},
+		{"no log", []string{"replay"}, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("run() = %v, want %v, stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Errorf("run() output = %q, want %q", stdout.String(), tt.wantOut)
+			}
+		})
+	}
+}
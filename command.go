@@ -0,0 +1,76 @@
+package quadpix
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+)
+
+// Op is the kind of a mutating operation on the tree.
+type Op uint8
+
+const (
+	// OpInsert inserts an entity in to the tree.
+	OpInsert Op = iota
+	// OpRemove removes an entity from the tree.
+	OpRemove
+	// OpUpdate moves an entity to new bounds within the tree.
+	OpUpdate
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpInsert:
+		return "insert"
+	case OpRemove:
+		return "remove"
+	case OpUpdate:
+		return "update"
+	default:
+		return fmt.Sprintf("Op(%d)", uint8(o))
+	}
+}
+
+// command is a single staged mutation of the tree.
+type command struct {
+	op     Op
+	entity *Entity
+	// rect is the new bounds of the entity for OpUpdate.
+	rect pixel.Rect
+}
+
+// apply runs the given command against the tree.
+func (q *Quadpix) apply(c command) error {
+	switch c.op {
+	case OpInsert:
+		if !q.accepts(c.entity.Rect) {
+			return fmt.Errorf("%w: entity %v at %v", ErrInvalidBounds, c.entity.ID, c.entity.Rect)
+		}
+		return q.InsertEntities(c.entity)
+	case OpRemove:
+		return q.Remove(c.entity)
+	case OpUpdate:
+		return q.Update(c.entity, c.rect)
+	default:
+		return fmt.Errorf("quadpix: unknown operation %v", c.op)
+	}
+}
+
+// CommandError is the error returned for a single failed command.
+//
+// Index is the position of the command in the order it was recorded.
+type CommandError struct {
+	Index  int
+	Op     Op
+	Entity *Entity
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command %d (%v): %v", e.Index, e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error of the command.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,340 @@
+package quadpix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/faiface/pixel"
+)
+
+// DebugHandler is an http.Handler serving debug views of registered trees.
+//
+// Each registered tree is served under its name:
+//
+//	GET /                        names of all registered trees
+//	GET /{name}/stats            Stats of the tree
+//	GET /{name}/nodes            every node of the tree in pre-order
+//	GET /{name}/validate         result of Validate
+//	GET /{name}/query?rect=...   entities intersecting minX,minY,maxX,maxY
+//	GET /{name}/point?x=...&y=.. entities intersecting a point
+//	GET /{name}/svg              SVG picture of the tree, with optional mode=depth|load,
+//	                             scale=n and query=minX,minY,maxX,maxY
+//
+// Queries and points outside the bounds of the tree are rejected with 400 Bad Request.
+//
+// Every request works on a copy of the tree taken while holding the lock given to Register,
+// so the tree can keep being changed as long as those changes hold the same lock.
+type DebugHandler struct {
+	mu    sync.RWMutex
+	trees map[string]debugTree
+}
+
+// debugTree is a tree registered with a DebugHandler.
+type debugTree struct {
+	tree *Quadpix
+	lock sync.Locker
+}
+
+// NewDebugHandler creates a new DebugHandler with no registered trees.
+func NewDebugHandler() *DebugHandler {
+	return &DebugHandler{
+		trees: make(map[string]debugTree),
+	}
+}
+
+// Register serves the given tree under the given name, replacing any tree already registered with that name.
+//
+// lock must be held by any code changing the tree while the handler is serving. If lock is nil the tree
+// must not be changed while a request is being served.
+func (h *DebugHandler) Register(name string, q *Quadpix, lock sync.Locker) {
+	h.mu.Lock()
+	h.trees[name] = debugTree{tree: q, lock: lock}
+	h.mu.Unlock()
+}
+
+// Unregister stops serving the tree registered with the given name.
+func (h *DebugHandler) Unregister(name string) {
+	h.mu.Lock()
+	delete(h.trees, name)
+	h.mu.Unlock()
+}
+
+// ServeHTTP serves the debug views.
+func (h *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		h.serveNames(w)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.RLock()
+	registered, ok := h.trees[parts[0]]
+	h.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// work on a copy so the tree can keep changing
+	if registered.lock != nil {
+		registered.lock.Lock()
+	}
+	tree := registered.tree.snapshot()
+	if registered.lock != nil {
+		registered.lock.Unlock()
+	}
+
+	query := r.URL.Query()
+
+	switch parts[1] {
+	case "stats":
+		writeJSON(w, toStatsJSON(tree.stats()))
+	case "nodes":
+		var nodes []nodeJSON
+		tree.walk(func(n *node) {
+			nodes = append(nodes, toNodeJSON(n.info()))
+		})
+		writeJSON(w, nodes)
+	case "validate":
+		result := struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error,omitempty"`
+		}{OK: true}
+		if err := tree.validate(); err != nil {
+			result.OK, result.Error = false, err.Error()
+		}
+		writeJSON(w, result)
+	case "query":
+		rect, err := parseRect(query.Get("rect"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !tree.reaches(rect) {
+			http.Error(w, fmt.Sprintf("rect %v is outside the tree bounds %v", rect, tree.rect), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, toEntitiesJSON(tree.retrieve(rect, nil).IntersectsWith(rect, tree.overlap)))
+	case "point":
+		v, err := parseFloats([]string{query.Get("x"), query.Get("y")})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rect := pixel.R(v[0], v[1], v[0], v[1])
+		if !validRect(rect) || !tree.reaches(rect) {
+			http.Error(w, fmt.Sprintf("point %v is outside the tree bounds %v", rect.Min, tree.rect), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, toEntitiesJSON(tree.retrieve(rect, nil).IntersectsWith(rect, tree.overlap)))
+	case "svg":
+		opts, err := parseExportOptions(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rect := range opts.Highlights {
+			if !tree.reaches(rect) {
+				http.Error(w, fmt.Sprintf("query %v is outside the tree bounds %v", rect, tree.rect), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		tree.WriteSVG(w, opts)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveNames writes the sorted names of all registered trees.
+func (h *DebugHandler) serveNames(w http.ResponseWriter) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.trees))
+	for name := range h.trees {
+		names = append(names, name)
+	}
+	h.mu.RUnlock()
+
+	sort.Strings(names)
+	writeJSON(w, names)
+}
+
+// snapshot creates a deep copy of the tree including copies of all of its entities.
+//
+// The copy only shares the Action functions of its entities with the tree, see Entity.copy.
+func (q *Quadpix) snapshot() *Quadpix {
+	s := &Quadpix{
+		config: q.config,
+	}
+	s.node = q.node.clone(s, make(map[*Entity]*Entity))
+
+	return s
+}
+
+// JSON form of Stats.
+type statsJSON struct {
+	Bounds          rectJSON `json:"bounds"`
+	MaxEntities     uint64   `json:"maxEntities"`
+	MaxDepth        uint16   `json:"maxDepth"`
+	Entities        int      `json:"entities"`
+	Stored          int      `json:"stored"`
+	Duplication     float64  `json:"duplication"`
+	Nodes           int      `json:"nodes"`
+	Leaves          int      `json:"leaves"`
+	Depth           int      `json:"depth"`
+	EmptyLeaves     int      `json:"emptyLeaves"`
+	FullLeaves      int      `json:"fullLeaves"`
+	MaxLeafEntities int      `json:"maxLeafEntities"`
+}
+
+func toStatsJSON(s Stats) statsJSON {
+	return statsJSON{
+		Bounds:          toRectJSON(s.Bounds),
+		MaxEntities:     s.MaxEntities,
+		MaxDepth:        s.MaxDepth,
+		Entities:        s.Entities,
+		Stored:          s.Stored,
+		Duplication:     s.Duplication(),
+		Nodes:           s.Nodes,
+		Leaves:          s.Leaves,
+		Depth:           s.Depth,
+		EmptyLeaves:     s.EmptyLeaves,
+		FullLeaves:      s.FullLeaves,
+		MaxLeafEntities: s.MaxLeafEntities,
+	}
+}
+
+// JSON form of a NodeInfo.
+type nodeJSON struct {
+	Bounds   rectJSON `json:"bounds"`
+	Depth    int      `json:"depth"`
+	Entities int      `json:"entities"`
+	Leaf     bool     `json:"leaf"`
+}
+
+func toNodeJSON(n NodeInfo) nodeJSON {
+	return nodeJSON{
+		Bounds:   toRectJSON(n.Bounds),
+		Depth:    n.Depth,
+		Entities: n.Entities,
+		Leaf:     n.Leaf,
+	}
+}
+
+func toEntitiesJSON(entities Entities) []entityJSON {
+	out := make([]entityJSON, 0, len(entities))
+	for _, e := range entities {
+		entity := entityJSON{
+			ID:     e.ID,
+			Bounds: toRectJSON(e.Rect),
+		}
+		for _, b := range e.Behaviours {
+			entity.Behaviours = append(entity.Behaviours, behaviourJSON{
+				Name:   b.Name,
+				Params: b.Params,
+			})
+		}
+		out = append(out, entity)
+	}
+	return out
+}
+
+// writeJSON writes v as the JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// maxDebugScale is the largest scale the svg view can be drawn at.
+const maxDebugScale = 64
+
+// parseExportOptions reads the svg view options from the request query.
+func parseExportOptions(query map[string][]string) (ExportOptions, error) {
+	get := func(key string) string {
+		if v := query[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	var opts ExportOptions
+
+	switch get("mode") {
+	case "", "depth":
+		opts.Mode = ColorByDepth
+	case "load":
+		opts.Mode = ColorByLoad
+	default:
+		return opts, fmt.Errorf("unknown mode %q", get("mode"))
+	}
+
+	if scale := get("scale"); scale != "" {
+		v, err := parseFloats([]string{scale})
+		if err != nil {
+			return opts, err
+		}
+		if !(v[0] >= 0 && v[0] <= maxDebugScale) {
+			return opts, fmt.Errorf("bad scale %q, want a number from 0 to %v", scale, maxDebugScale)
+		}
+		opts.Scale = v[0]
+	}
+
+	if q := get("query"); q != "" {
+		rect, err := parseRect(q)
+		if err != nil {
+			return opts, err
+		}
+		opts.Highlights = []pixel.Rect{rect}
+	}
+
+	return opts, nil
+}
+
+// parseRect parses a pixel.Rect written as minX,minY,maxX,maxY.
+func parseRect(s string) (pixel.Rect, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return pixel.Rect{}, fmt.Errorf("bad rect %q, want minX,minY,maxX,maxY", s)
+	}
+
+	v, err := parseFloats(parts)
+	if err != nil {
+		return pixel.Rect{}, err
+	}
+
+	rect := pixel.R(v[0], v[1], v[2], v[3])
+	if !validRect(rect) {
+		return pixel.Rect{}, fmt.Errorf("bad rect %q", s)
+	}
+	return rect, nil
+}
+
+// parseFloats parses each string as a float64.
+func parseFloats(s []string) ([]float64, error) {
+	v := make([]float64, len(s))
+	for i := range s {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s[i]), 64)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = f
+	}
+	return v, nil
+}
@@ -0,0 +1,175 @@
+package quadpix
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestDebugHandler(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+	tree.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+		&Entity{ID: 3, Rect: pixel.R(350, 250, 450, 350)},
+	)
+
+	var mu sync.Mutex
+	h := NewDebugHandler()
+	h.Register("level", tree, &mu)
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		method      string
+		wantStatus  int
+		wantType    string
+		wantContain string
+	}{
+		{"names", "/", http.MethodGet, http.StatusOK, "application/json", `["level"]`},
+		{"stats", "/level/stats", http.MethodGet, http.StatusOK, "application/json", `"entities":3`},
+		{"nodes", "/level/nodes", http.MethodGet, http.StatusOK, "application/json", `"leaf":true`},
+		{"validate", "/level/validate", http.MethodGet, http.StatusOK, "application/json", `"ok":true`},
+		{"query", "/level/query?rect=0,0,100,100", http.MethodGet, http.StatusOK, "application/json", `"id":1`},
+		{"point", "/level/point?x=400&y=300", http.MethodGet, http.StatusOK, "application/json", `"id":3`},
+		{"svg", "/level/svg?mode=load&scale=0.5&query=0,0,100,100", http.MethodGet, http.StatusOK, "image/svg+xml", "<svg"},
+		{"bad rect", "/level/query?rect=0,0,100", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"bad mode", "/level/svg?mode=rainbow", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"rect outside", "/level/query?rect=5000,5000,6000,6000", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"point outside", "/level/point?x=-10&y=300", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"nan point", "/level/point?x=NaN&y=300", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"svg query outside", "/level/svg?query=5000,5000,6000,6000", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"nan scale", "/level/svg?scale=NaN", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"inf scale", "/level/svg?scale=Inf", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"huge scale", "/level/svg?scale=1e9", http.MethodGet, http.StatusBadRequest, "", ""},
+		{"unknown tree", "/missing/stats", http.MethodGet, http.StatusNotFound, "", ""},
+		{"unknown view", "/level/missing", http.MethodGet, http.StatusNotFound, "", ""},
+		{"post", "/level/stats", http.MethodPost, http.StatusMethodNotAllowed, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("DebugHandler %v %v status = %v, want %v", tt.method, tt.path, resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantType != "" && resp.Header.Get("Content-Type") != tt.wantType {
+				t.Errorf("DebugHandler %v Content-Type = %v, want %v", tt.path, resp.Header.Get("Content-Type"), tt.wantType)
+			}
+
+			var body bytes.Buffer
+			if _, err := body.ReadFrom(resp.Body); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(body.String(), tt.wantContain) {
+				t.Errorf("DebugHandler %v body = %v, want it to contain %v", tt.path, body.String(), tt.wantContain)
+			}
+		})
+	}
+}
+
+func TestDebugHandler_concurrentUpdates(t *testing.T) {
+	tree := New(800, 600, 4, 6)
+	entities := make([]*Entity, 50)
+	for i := range entities {
+		x := float64(i * 15)
+		entities[i] = &Entity{ID: uint64(i), Rect: pixel.R(x, x/2, x+10, x/2+10)}
+	}
+	tree.InsertEntities(entities...)
+
+	var mu sync.Mutex
+	h := NewDebugHandler()
+	h.Register("live", tree, &mu)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			mu.Lock()
+			e := entities[i%len(entities)]
+			r := e.Rect.Moved(pixel.V(1, 1))
+			if r.Max.X > 800 || r.Max.Y > 600 {
+				r = pixel.R(0, 0, 10, 10)
+			}
+			tree.Update(e, r)
+			mu.Unlock()
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live/validate", nil))
+
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if !result.OK {
+			t.Fatalf("DebugHandler served an invalid tree: %v", result.Error)
+		}
+	}
+	<-done
+
+	h.Unregister("live")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live/stats", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DebugHandler after Unregister status = %v, want %v", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestQuadpix_snapshot(t *testing.T) {
+	tree := New(800, 600, 4, 6)
+	var called bool
+	entity := &Entity{
+		ID:         1,
+		Rect:       pixel.R(10, 10, 20, 20),
+		Actions:    []Action{func() { called = true }},
+		Behaviours: []Behaviour{{Name: "move", Params: map[string]string{"speed": "1"}}},
+	}
+	if err := tree.InsertEntities(entity); err != nil {
+		t.Fatal(err)
+	}
+
+	s := tree.snapshot()
+	copies := s.all()
+	if len(copies) != 1 || copies[0] == entity {
+		t.Fatalf("snapshot() shares its entities with the tree")
+	}
+
+	c := copies[0]
+	if len(c.Actions) != 1 {
+		t.Fatalf("snapshot() dropped the entity's Actions: %v", c.Actions)
+	}
+	c.Actions[0]()
+	if !called {
+		t.Fatalf("snapshot() did not keep the entity's Action functions")
+	}
+
+	c.Behaviours[0].Params["speed"] = "2"
+	c.Behaviours[0].Name = "stop"
+	if entity.Behaviours[0].Params["speed"] != "1" || entity.Behaviours[0].Name != "move" {
+		t.Fatalf("changing the snapshot changed the tree's Behaviours: %v", entity.Behaviours)
+	}
+}
@@ -0,0 +1,58 @@
+package quadpix
+
+import (
+	"github.com/faiface/pixel"
+)
+
+// EnableDirtyTracking starts collecting the regions of the tree changed by Insert, Remove and Update.
+//
+// The bounds of every inserted and removed entity, and the old and new bounds of every updated entity,
+// are added to the dirty regions. Overlapping regions are merged and all regions are clamped to the
+// root bounds of the tree. Regions keep being collected until ClearDirty is called.
+func (q *Quadpix) EnableDirtyTracking() {
+	q.trackDirty = true
+}
+
+// DisableDirtyTracking stops collecting dirty regions and clears the current ones.
+func (q *Quadpix) DisableDirtyTracking() {
+	q.trackDirty = false
+	q.dirty = nil
+}
+
+// DirtyRegions returns the regions of the tree changed since dirty tracking was enabled or ClearDirty was last called.
+//
+// No two of the returned regions overlap.
+func (q *Quadpix) DirtyRegions() []pixel.Rect {
+	regions := make([]pixel.Rect, len(q.dirty))
+	copy(regions, q.dirty)
+	return regions
+}
+
+// ClearDirty clears all dirty regions, usually once they have been redrawn.
+func (q *Quadpix) ClearDirty() {
+	q.dirty = q.dirty[:0]
+}
+
+// markDirty adds the given pixel.Rect to the dirty regions if dirty tracking is enabled.
+func (q *Quadpix) markDirty(rect pixel.Rect) {
+	if !q.trackDirty || !rect.Intersects(q.rect) {
+		return
+	}
+
+	rect = rect.Intersect(q.rect)
+
+	// merge every region the new region overlaps in to it, repeating as the merged region grows
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(q.dirty); i++ {
+			if q.dirty[i].Intersects(rect) {
+				rect = rect.Union(q.dirty[i])
+				q.dirty = append(q.dirty[:i], q.dirty[i+1:]...)
+				merged = true
+				i--
+			}
+		}
+	}
+
+	q.dirty = append(q.dirty, rect)
+}
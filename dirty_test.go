@@ -0,0 +1,97 @@
+package quadpix
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestQuadpix_DirtyRegions(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(q *Quadpix, e *Entity)
+		want   []pixel.Rect
+	}{
+		{
+			name:   "insert",
+			change: func(q *Quadpix, e *Entity) { q.Insert(pixel.R(100, 100, 120, 120)) },
+			want:   []pixel.Rect{pixel.R(100, 100, 120, 120)},
+		},
+		{
+			name:   "remove",
+			change: func(q *Quadpix, e *Entity) { q.Remove(e) },
+			want:   []pixel.Rect{pixel.R(10, 10, 50, 50)},
+		},
+		{
+			name:   "update far away",
+			change: func(q *Quadpix, e *Entity) { q.Update(e, pixel.R(700, 500, 740, 540)) },
+			want:   []pixel.Rect{pixel.R(10, 10, 50, 50), pixel.R(700, 500, 740, 540)},
+		},
+		{
+			name:   "update overlapping",
+			change: func(q *Quadpix, e *Entity) { q.Update(e, pixel.R(30, 30, 70, 70)) },
+			want:   []pixel.Rect{pixel.R(10, 10, 70, 70)},
+		},
+		{
+			name: "merged chain",
+			change: func(q *Quadpix, e *Entity) {
+				q.Insert(pixel.R(100, 0, 110, 10))
+				q.Insert(pixel.R(200, 0, 210, 10))
+				// overlaps both earlier regions
+				q.Insert(pixel.R(105, 5, 205, 8))
+			},
+			want: []pixel.Rect{pixel.R(100, 0, 210, 10)},
+		},
+		{
+			name:   "clamped to root",
+			change: func(q *Quadpix, e *Entity) { q.Insert(pixel.R(780, 580, 900, 700)) },
+			want:   []pixel.Rect{pixel.R(780, 580, 800, 600)},
+		},
+		{
+			name:   "failed remove",
+			change: func(q *Quadpix, e *Entity) { q.Remove(&Entity{ID: 9, Rect: pixel.R(1, 1, 2, 2)}) },
+			want:   []pixel.Rect{},
+		},
+		{
+			name: "load",
+			change: func(q *Quadpix, e *Entity) {
+				q.Insert(pixel.R(100, 100, 120, 120))
+				json.Unmarshal([]byte(`{"bounds":{"min":{"x":0,"y":0},"max":{"x":400,"y":300}},"maxEntities":2,"maxDepth":4}`), q)
+			},
+			want: []pixel.Rect{pixel.R(0, 0, 400, 300)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := New(800, 600, 2, 4)
+			e := &Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)}
+			tree.InsertEntities(e)
+
+			// nothing is tracked until enabled
+			if got := tree.DirtyRegions(); len(got) != 0 {
+				t.Fatalf("Quadpix.DirtyRegions() before EnableDirtyTracking = %v", got)
+			}
+
+			tree.EnableDirtyTracking()
+			tt.change(tree, e)
+
+			if got := tree.DirtyRegions(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Quadpix.DirtyRegions() = %v, want %v", got, tt.want)
+			}
+
+			tree.ClearDirty()
+			if got := tree.DirtyRegions(); len(got) != 0 {
+				t.Errorf("Quadpix.DirtyRegions() after ClearDirty = %v, want none", got)
+			}
+
+			tree.DisableDirtyTracking()
+			tree.Insert(pixel.R(0, 0, 10, 10))
+			if got := tree.DirtyRegions(); len(got) != 0 {
+				t.Errorf("Quadpix.DirtyRegions() after DisableDirtyTracking = %v, want none", got)
+			}
+		})
+	}
+}
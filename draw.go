@@ -0,0 +1,176 @@
+package quadpix
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+)
+
+// DefaultPalette is the list of colours used for node bounds by depth when no Palette is given in DrawOptions.
+var DefaultPalette = []pixel.RGBA{
+	pixel.RGB(0.25, 0.55, 1),
+	pixel.RGB(0.2, 0.8, 0.4),
+	pixel.RGB(1, 0.8, 0.2),
+	pixel.RGB(1, 0.5, 0.2),
+	pixel.RGB(0.9, 0.3, 0.6),
+	pixel.RGB(0.6, 0.4, 1),
+}
+
+// default colours used by Draw.
+var (
+	defaultEmptyColor     = pixel.RGB(0.2, 0.8, 0.4)
+	defaultFullColor      = pixel.RGB(1, 0.2, 0.2)
+	defaultEntityColor    = pixel.RGB(1, 1, 1)
+	defaultHighlightColor = pixel.RGB(1, 1, 0)
+)
+
+// ColorMode selects how node bounds are coloured when drawing the tree.
+type ColorMode uint8
+
+const (
+	// ColorByDepth colours each node by its depth in the tree.
+	ColorByDepth ColorMode = iota
+	// ColorByLoad colours each node by how full it is, from empty to max entities.
+	ColorByLoad
+)
+
+// DrawOptions holds the options used by Draw.
+//
+// Any colour left as the zero pixel.RGBA is replaced with a default colour.
+type DrawOptions struct {
+	// Mode selects how node bounds are coloured.
+	Mode ColorMode
+
+	// Palette is the list of colours used for nodes by depth with ColorByDepth.
+	// Nodes deeper then the palette wrap back to the first colour. DefaultPalette is used if empty.
+	Palette []pixel.RGBA
+
+	// EmptyColor and FullColor are the colours of empty and full nodes with ColorByLoad.
+	EmptyColor, FullColor pixel.RGBA
+
+	// EntityColor is the colour of entity bounds.
+	EntityColor pixel.RGBA
+
+	// Highlights is a list of query bounds to draw along with every entity each one intersects.
+	Highlights []pixel.Rect
+
+	// HighlightColor is the colour of highlighted query bounds and entities.
+	HighlightColor pixel.RGBA
+
+	// Thickness is the line thickness of all drawn bounds. Defaults to 1.
+	Thickness float64
+
+	// HideNodes and HideEntities stop node and entity bounds from being drawn.
+	HideNodes, HideEntities bool
+}
+
+// Draw draws the tree's node bounds, entity bounds and any highlighted queries to the given imdraw.IMDraw.
+//
+// Draw is meant for debugging and tuning the tree. Like Insert and Remove it reads the tree directly
+// so it must not be called while the tree is being changed.
+func (q *Quadpix) Draw(imd *imdraw.IMDraw, opts DrawOptions) {
+	for _, s := range q.shapes(opts) {
+		imd.Color = s.color
+		rectangle(imd, s.rect, s.thickness)
+	}
+}
+
+// shape is a single outlined pixel.Rect to draw.
+type shape struct {
+	rect      pixel.Rect
+	color     pixel.RGBA
+	thickness float64
+}
+
+// shapes returns every outline to draw for the tree with the given options in drawing order.
+func (q *Quadpix) shapes(opts DrawOptions) (shapes []shape) {
+	opts.defaults()
+
+	add := func(rect pixel.Rect, color pixel.RGBA) {
+		shapes = append(shapes, shape{
+			rect:      rect,
+			color:     color,
+			thickness: opts.Thickness,
+		})
+	}
+
+	// node bounds
+	if !opts.HideNodes {
+		q.walk(func(n *node) {
+			add(n.rect, opts.nodeColor(n))
+		})
+	}
+
+	// entity bounds
+	if !opts.HideEntities {
+		for _, e := range q.all() {
+			add(e.Rect, opts.EntityColor)
+		}
+	}
+
+	// query highlights
+	for _, rect := range opts.Highlights {
+		add(rect, opts.HighlightColor)
+
+		// a highlight that does not reach the tree has no nodes to search
+		if !q.reaches(rect) {
+			continue
+		}
+		for _, e := range q.retrieve(rect, nil).IntersectsWith(rect, q.overlap) {
+			add(e.Rect, opts.HighlightColor)
+		}
+	}
+
+	return
+}
+
+// defaults fills in the default value for any unset option.
+func (o *DrawOptions) defaults() {
+	if len(o.Palette) == 0 {
+		o.Palette = DefaultPalette
+	}
+	if o.EmptyColor == (pixel.RGBA{}) {
+		o.EmptyColor = defaultEmptyColor
+	}
+	if o.FullColor == (pixel.RGBA{}) {
+		o.FullColor = defaultFullColor
+	}
+	if o.EntityColor == (pixel.RGBA{}) {
+		o.EntityColor = defaultEntityColor
+	}
+	if o.HighlightColor == (pixel.RGBA{}) {
+		o.HighlightColor = defaultHighlightColor
+	}
+	if o.Thickness <= 0 {
+		o.Thickness = 1
+	}
+}
+
+// nodeColor returns the colour of the given node for the options colour mode.
+func (o *DrawOptions) nodeColor(n *node) pixel.RGBA {
+	if o.Mode == ColorByLoad {
+		return o.EmptyColor.Scaled(1 - n.load()).Add(o.FullColor.Scaled(n.load()))
+	}
+	return o.Palette[int(n.depth)%len(o.Palette)]
+}
+
+// load returns how full this node is from 0 for empty to 1 for max entities or more.
+func (n *node) load() float64 {
+	if n.tree.maxEntities == 0 {
+		if len(n.entities) > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	load := float64(len(n.entities)) / float64(n.tree.maxEntities)
+	if load > 1 {
+		return 1
+	}
+	return load
+}
+
+// rectangle draws the outline of the given pixel.Rect.
+func rectangle(imd *imdraw.IMDraw, rect pixel.Rect, thickness float64) {
+	imd.Push(rect.Min, rect.Max)
+	imd.Rectangle(thickness)
+}
@@ -0,0 +1,144 @@
+package quadpix
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+)
+
+// recordTarget is a headless pixel.Target that records every vertex drawn to it.
+type recordTarget struct {
+	vertices pixel.TrianglesData
+}
+
+func (r *recordTarget) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	tri := pixel.MakeTrianglesData(t.Len())
+	tri.Update(t)
+	return &recordTriangles{TrianglesData: tri, target: r}
+}
+
+func (r *recordTarget) MakePicture(p pixel.Picture) pixel.TargetPicture {
+	return nil
+}
+
+type recordTriangles struct {
+	*pixel.TrianglesData
+	target *recordTarget
+}
+
+func (t *recordTriangles) Draw() {
+	t.target.vertices = append(t.target.vertices, *t.TrianglesData...)
+}
+
+// drawn draws the tree with the given options and returns the recorded vertices.
+func drawn(q *Quadpix, opts DrawOptions) pixel.TrianglesData {
+	imd := imdraw.New(nil)
+	q.Draw(imd, opts)
+
+	target := &recordTarget{}
+	imd.Draw(target)
+	return target.vertices
+}
+
+// colorCount counts the vertices of each colour.
+func colorCount(vertices pixel.TrianglesData) map[pixel.RGBA]int {
+	count := make(map[pixel.RGBA]int)
+	for _, v := range vertices {
+		count[v.Color]++
+	}
+	return count
+}
+
+func TestQuadpix_Draw(t *testing.T) {
+	// vertices used for one outlined rectangle
+	imd := imdraw.New(nil)
+	rectangle(imd, pixel.R(0, 0, 1, 1), 1)
+	target := &recordTarget{}
+	imd.Draw(target)
+	perRect := len(target.vertices)
+	if perRect == 0 {
+		t.Fatalf("rectangle() drew no vertices")
+	}
+
+	tree := New(800, 600, 1, 4)
+	tree.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+	)
+
+	tests := []struct {
+		name   string
+		opts   DrawOptions
+		colors map[pixel.RGBA]int
+	}{
+		{
+			name: "nodes by depth",
+			opts: DrawOptions{HideEntities: true},
+			colors: map[pixel.RGBA]int{
+				DefaultPalette[0]: perRect,
+				DefaultPalette[1]: 4 * perRect,
+			},
+		},
+		{
+			name: "nodes by load",
+			opts: DrawOptions{Mode: ColorByLoad, HideEntities: true, EmptyColor: pixel.RGB(0, 0, 1), FullColor: pixel.RGB(1, 0, 0)},
+			colors: map[pixel.RGBA]int{
+				pixel.RGB(0, 0, 1): 3 * perRect,
+				pixel.RGB(1, 0, 0): 2 * perRect,
+			},
+		},
+		{
+			name: "entities",
+			opts: DrawOptions{HideNodes: true, EntityColor: pixel.RGB(0, 1, 0)},
+			colors: map[pixel.RGBA]int{
+				pixel.RGB(0, 1, 0): 2 * perRect,
+			},
+		},
+		{
+			name: "query highlight",
+			opts: DrawOptions{HideNodes: true, HideEntities: true, Highlights: []pixel.Rect{pixel.R(0, 0, 20, 20)}},
+			colors: map[pixel.RGBA]int{
+				defaultHighlightColor: 2 * perRect,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vertices := drawn(tree, tt.opts)
+
+			got := colorCount(vertices)
+			if len(got) != len(tt.colors) {
+				t.Errorf("Quadpix.Draw() got colours %v, want %v", got, tt.colors)
+			}
+			for color, want := range tt.colors {
+				if got[color] != want {
+					t.Errorf("Quadpix.Draw() got %v vertices of %v, want %v", got[color], color, want)
+				}
+			}
+
+			// all geometry must be within the root bounds and the line thickness
+			bounds := pixel.R(-1, -1, 801, 601)
+			for _, v := range vertices {
+				if !bounds.Contains(v.Position) {
+					t.Errorf("Quadpix.Draw() vertex %v outside of tree bounds", v.Position)
+				}
+			}
+		})
+	}
+}
+
+func TestQuadpix_DrawHighlightOutside(t *testing.T) {
+	tree := New(800, 600, 1, 4)
+	tree.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+	)
+
+	// a highlight that does not reach the split root must not search it
+	opts := DrawOptions{HideNodes: true, HideEntities: true, Highlights: []pixel.Rect{pixel.R(900, 700, 950, 750)}}
+	opts.defaults()
+	if shapes := tree.shapes(opts); len(shapes) != 1 {
+		t.Errorf("Quadpix.shapes() = %v, want only the highlight", shapes)
+	}
+}
@@ -60,27 +60,19 @@ func (e Entities) Contains(entity *Entity) bool {
 }
 
 // Intersect checks if any entity within entities intersects with the given pixel.Rect.
+//
+// Intersect uses the default Overlap rules, the same as pixel.Rect.Intersects, and not the Edges and Epsilon
+// Options of any tree. Use IntersectWith to check under a tree's rules.
 func (e Entities) Intersect(rect pixel.Rect) bool {
-	// check if any entities returned intersect the given point
-	for i := range e {
-		// check for intersect
-		if e[i].Intersects(rect) {
-			return true
-		}
-	}
-	return false
+	return e.IntersectWith(rect, Overlap{})
 }
 
 // Intersects returns a list of all entities that the given Rect intersects with within the entities list.
-func (e Entities) Intersects(rect pixel.Rect) (entities Entities) {
-	// check if any entities returned intersect the given point and if they do add them to the return list
-	for i := range e {
-		// add to list if they intersect
-		if e[i].Intersects(rect) {
-			entities = append(entities, e[i])
-		}
-	}
-	return
+//
+// Intersects uses the default Overlap rules, the same as pixel.Rect.Intersects, and not the Edges and Epsilon
+// Options of any tree. Use IntersectsWith to check under a tree's rules.
+func (e Entities) Intersects(rect pixel.Rect) Entities {
+	return e.IntersectsWith(rect, Overlap{})
 }
 
 // Action is a function type which can be stored in an Entity for future use.
@@ -90,11 +82,15 @@ type Action func()
 //
 // Entity holds a pixel.Rect as its bounding box and an ID and a list of posable Action functions.
 // ID is set to be a random uint64 number on creation of an Entity.
+//
+// Behaviours reference registered Actions by name so they can be saved with the tree.
+// When an entity with Behaviours and no Actions is inserted its Actions are created from its Behaviours.
 type Entity struct {
 	pixel.Rect
 
-	ID      uint64
-	Actions []Action
+	ID         uint64
+	Actions    []Action
+	Behaviours []Behaviour
 }
 
 // E creates a new Entity with the given pixel.Rect bounding box and a posable list of Action functions.
@@ -108,6 +104,57 @@ func E(rect pixel.Rect, actions ...Action) *Entity {
 	}
 }
 
+// Bind sets the entity's Actions to the Actions created from its Behaviours.
+//
+// Entities with no Behaviours are not changed. If any Behaviour can not be bound an error is returned
+// and the entity's Actions are left unchanged.
+func (e *Entity) Bind() error {
+	if len(e.Behaviours) == 0 {
+		return nil
+	}
+
+	actions := make([]Action, 0, len(e.Behaviours))
+	for _, b := range e.Behaviours {
+		action, err := b.Bind()
+		if err != nil {
+			return err
+		}
+		actions = append(actions, action)
+	}
+
+	e.Actions = actions
+
+	return nil
+}
+
+// copy returns a copy of this entity.
+//
+// The Actions and Behaviours lists and the Params of each Behaviour are copied, the Action functions are shared.
+func (e *Entity) copy() *Entity {
+	c := &Entity{
+		Rect: e.Rect,
+		ID:   e.ID,
+	}
+
+	if e.Actions != nil {
+		c.Actions = append([]Action(nil), e.Actions...)
+	}
+	if e.Behaviours != nil {
+		c.Behaviours = make([]Behaviour, len(e.Behaviours))
+		for i, b := range e.Behaviours {
+			c.Behaviours[i] = Behaviour{Name: b.Name}
+			if b.Params != nil {
+				c.Behaviours[i].Params = make(map[string]string, len(b.Params))
+				for k, v := range b.Params {
+					c.Behaviours[i].Params[k] = v
+				}
+			}
+		}
+	}
+
+	return c
+}
+
 // IsEqual checks if the given entity is equal to this entity.
 //
 // IsEqual checks two things:
@@ -11,4 +11,43 @@ var (
 
 	// ErrNoEntitiesGiven error
 	ErrNoEntitiesGiven = errors.New("no entities given to InsertEntities()")
+
+	// ErrTxDone error
+	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+	// ErrNothingToUndo error
+	ErrNothingToUndo = errors.New("no steps in history to undo")
+
+	// ErrNothingToRedo error
+	ErrNothingToRedo = errors.New("no steps in history to redo")
+
+	// ErrInvalidBounds error
+	ErrInvalidBounds = errors.New("invalid bounds")
+
+	// ErrUnknownAction error
+	ErrUnknownAction = errors.New("no action registered with name")
+
+	// ErrUnregisteredAction error
+	ErrUnregisteredAction = errors.New("action has not been registered with RegisterAction()")
+
+	// ErrInvalidFormat error
+	ErrInvalidFormat = errors.New("invalid quadpix binary snapshot")
+
+	// ErrUnsupportedVersion error
+	ErrUnsupportedVersion = errors.New("unsupported quadpix binary snapshot version")
+
+	// ErrInvalidTree error
+	ErrInvalidTree = errors.New("invalid tree structure")
+
+	// ErrReplayMismatch error
+	ErrReplayMismatch = errors.New("replay result does not match the recording")
+
+	// ErrInvalidOption error
+	ErrInvalidOption = errors.New("invalid tree option")
+
+	// ErrInvalidQuadkey error
+	ErrInvalidQuadkey = errors.New("invalid quadkey")
+
+	// ErrImageTooLarge error
+	ErrImageTooLarge = errors.New("image too large")
 )
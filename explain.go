@@ -0,0 +1,158 @@
+package quadpix
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+// Explanation describes the work done by a Retrieve or Intersects query.
+type Explanation struct {
+	Rect pixel.Rect
+
+	// Nodes holds every node visited by the query in the order they were visited and
+	// Leaves the leafs reached by the query.
+	Nodes  []NodeInfo
+	Leaves []NodeInfo
+
+	// QuadrantTests is the number of child bounds tested against the query bounds.
+	QuadrantTests int
+
+	// EntitiesTested is the number of entities read from all reached nodes, Duplicates the number of those
+	// that were dropped for already being found in another leaf, Retrieved the number returned by Retrieve
+	// and Intersected the number of those returned by Intersects.
+	EntitiesTested int
+	Duplicates     int
+	Retrieved      int
+	Intersected    int
+
+	// OverloadedLeaves is the number of reached leafs at the max depth holding more then max entities.
+	// These leafs can not split any further so every query reaching them tests all of there entities.
+	OverloadedLeaves int
+
+	// Duration is the time the query took to run without the work of explaining it.
+	Duration time.Duration
+}
+
+// String returns a readable summary of the Explanation.
+func (e Explanation) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "query: %v\n", e.Rect)
+	fmt.Fprintf(&b, "duration: %v\n", e.Duration)
+	fmt.Fprintf(&b, "nodes visited: %d (quadrant tests %d)\n", len(e.Nodes), e.QuadrantTests)
+	fmt.Fprintf(&b, "leaves reached: %d (overloaded %d)\n", len(e.Leaves), e.OverloadedLeaves)
+	fmt.Fprintf(&b, "entities tested: %d (duplicates %d)\n", e.EntitiesTested, e.Duplicates)
+	fmt.Fprintf(&b, "retrieved: %d\nintersected: %d\n", e.Retrieved, e.Intersected)
+
+	for _, n := range e.Leaves {
+		fmt.Fprintf(&b, "  leaf %v depth %d entities %d\n", n.Bounds, n.Depth, n.Entities)
+	}
+
+	return b.String()
+}
+
+// Explain runs a query for the given pixel.Rect and returns an Explanation of the work it did.
+//
+// A query that does not reach the tree returns an empty Explanation.
+//
+// Explain returns a channel of an Explanation. This is due to the fact that all Read-Only operations in Quadpix are run on there own thread.
+func (q *Quadpix) Explain(rect pixel.Rect) <-chan Explanation {
+	out := make(chan Explanation)
+
+	go func() {
+		out <- q.explain(rect)
+		close(out)
+	}()
+
+	return out
+}
+
+// explain runs the query once to time it and then again while recording the work it does.
+func (q *Quadpix) explain(rect pixel.Rect) Explanation {
+	// a query that does not reach the tree has no nodes to search,
+	// loose trees are still searched as their root can hold entities outside of its bounds
+	if q.looseness == 0 && !q.reaches(rect) {
+		return Explanation{Rect: rect}
+	}
+
+	start := time.Now()
+	q.retrieve(rect, nil).IntersectsWith(rect, q.overlap)
+	duration := time.Since(start)
+
+	e := Explanation{
+		Rect:     rect,
+		Duration: duration,
+	}
+
+	entities := q.node.explain(rect, &e)
+	e.Retrieved = len(entities)
+	e.Intersected = len(entities.IntersectsWith(rect, q.overlap))
+
+	return e
+}
+
+// explain follows the same path as retrieve while recording the work done in the given Explanation.
+func (n *node) explain(rect pixel.Rect, e *Explanation) (entities Entities) {
+	if n.tree.looseness > 0 {
+		return n.explainLoose(rect, e, nil)
+	}
+
+	e.Nodes = append(e.Nodes, n.info())
+
+	// check for a leaf node
+	if len(n.children) > 0 {
+		e.QuadrantTests += len(n.children)
+
+		// get all nodes pixel.Rect intersects
+		nodes := n.getQuadrant(rect)
+		if len(nodes) == 0 {
+			panic(ErrNoNodeFound)
+		}
+
+		// merge found entities counting the ones already found in another leaf
+		for i := range nodes {
+			found := nodes[i].explain(rect, e)
+			before := len(entities)
+			entities = entities.Merge(found)
+			e.Duplicates += len(found) - (len(entities) - before)
+		}
+		return
+	}
+
+	e.leaf(n)
+
+	return n.entities
+}
+
+// explainLoose follows the same path as retrieveLoose while recording the work done in the given Explanation.
+func (n *node) explainLoose(rect pixel.Rect, e *Explanation, entities Entities) Entities {
+	e.Nodes = append(e.Nodes, n.info())
+
+	if len(n.children) == 0 {
+		e.leaf(n)
+		return append(entities, n.entities...)
+	}
+
+	// entities held by a branch are tested by every query reaching it
+	e.EntitiesTested += len(n.entities)
+	entities = append(entities, n.entities...)
+
+	e.QuadrantTests += len(n.children)
+	for _, child := range n.reach(rect) {
+		entities = child.explainLoose(rect, e, entities)
+	}
+
+	return entities
+}
+
+// leaf records the given leaf as reached by the query.
+func (e *Explanation) leaf(n *node) {
+	e.Leaves = append(e.Leaves, n.info())
+	e.EntitiesTested += len(n.entities)
+	if n.depth >= n.tree.maxDepth && uint64(len(n.entities)) > n.tree.maxEntities {
+		e.OverloadedLeaves++
+	}
+}
@@ -0,0 +1,112 @@
+package quadpix
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestQuadpix_Explain(t *testing.T) {
+	tree := New(800, 600, 2, 1)
+	tree.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+		&Entity{ID: 3, Rect: pixel.R(350, 250, 450, 350)},
+		&Entity{ID: 4, Rect: pixel.R(60, 60, 70, 70)},
+		&Entity{ID: 5, Rect: pixel.R(80, 80, 90, 90)},
+	)
+
+	tests := []struct {
+		name string
+		rect pixel.Rect
+		want Explanation
+	}{
+		{
+			name: "whole tree",
+			rect: pixel.R(0, 0, 800, 600),
+			want: Explanation{
+				QuadrantTests:    4,
+				EntitiesTested:   8,
+				Duplicates:       3,
+				Retrieved:        5,
+				Intersected:      5,
+				OverloadedLeaves: 1,
+			},
+		},
+		{
+			name: "one leaf",
+			rect: pixel.R(0, 0, 20, 20),
+			want: Explanation{
+				QuadrantTests:    4,
+				EntitiesTested:   4,
+				Duplicates:       0,
+				Retrieved:        4,
+				Intersected:      1,
+				OverloadedLeaves: 1,
+			},
+		},
+		{
+			name: "empty space",
+			rect: pixel.R(700, 10, 750, 20),
+			want: Explanation{
+				QuadrantTests:  4,
+				EntitiesTested: 1,
+				Retrieved:      1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := <-tree.Explain(tt.rect)
+
+			if got.Rect != tt.rect || got.QuadrantTests != tt.want.QuadrantTests || got.EntitiesTested != tt.want.EntitiesTested ||
+				got.Duplicates != tt.want.Duplicates || got.Retrieved != tt.want.Retrieved ||
+				got.Intersected != tt.want.Intersected || got.OverloadedLeaves != tt.want.OverloadedLeaves {
+				t.Errorf("Quadpix.Explain() = %+v, want %+v", got, tt.want)
+			}
+
+			// the explanation must agree with the query it explains
+			if retrieved := len(<-tree.Retrieve(tt.rect)); got.Retrieved != retrieved {
+				t.Errorf("Quadpix.Explain().Retrieved = %v, Retrieve() returned %v", got.Retrieved, retrieved)
+			}
+			if len(got.Nodes) != len(got.Leaves)+1 || got.Nodes[0].Bounds != tree.rect {
+				t.Errorf("Quadpix.Explain() visited %v nodes and %v leafs, want the root and each leaf", len(got.Nodes), len(got.Leaves))
+			}
+			if !strings.Contains(got.String(), "leaves reached") {
+				t.Errorf("Explanation.String() = %q", got.String())
+			}
+		})
+	}
+}
+
+func TestQuadpix_ExplainOutsideRoot(t *testing.T) {
+	tree := New(800, 600, 2, 1)
+	tree.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+		&Entity{ID: 3, Rect: pixel.R(350, 250, 450, 350)},
+	)
+	if len(tree.children) == 0 {
+		t.Fatal("tree did not split")
+	}
+
+	rect := pixel.R(900, 700, 950, 750)
+	got := <-tree.Explain(rect)
+	if got.Rect != rect || len(got.Nodes) != 0 || got.Retrieved != 0 || got.Intersected != 0 {
+		t.Errorf("Quadpix.Explain() = %+v, want an empty Explanation", got)
+	}
+
+	// loose trees are still searched for entities outside of the root
+	loose := New(800, 600, 2, 1, Loose(1))
+	outside := &Entity{ID: 4, Rect: pixel.R(790, 590, 900, 700)}
+	loose.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+		outside,
+	)
+	if got := <-loose.Explain(rect); got.Retrieved == 0 || got.Retrieved != len(<-loose.Retrieve(rect)) {
+		t.Errorf("Quadpix.Explain().Retrieved = %v on a loose tree, want %v", got.Retrieved, len(<-loose.Retrieve(rect)))
+	}
+}
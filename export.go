@@ -0,0 +1,181 @@
+package quadpix
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// MaxImagePixels is the largest number of pixels Image and WritePNG draw.
+const MaxImagePixels = 1 << 26
+
+// ExportOptions holds the options used by WriteSVG and WritePNG.
+//
+// The embedded DrawOptions select what is drawn and how it is coloured, the same as with Draw.
+type ExportOptions struct {
+	DrawOptions
+
+	// Scale is the number of image pixels per unit of the tree's bounds. Defaults to 1.
+	Scale float64
+
+	// Background is the colour the image is filled with before drawing. Defaults to transparent.
+	Background pixel.RGBA
+}
+
+// WriteSVG writes a picture of the tree's node bounds, entity bounds and any highlighted queries to w as SVG.
+//
+// The picture covers the root bounds of the tree with the y axis flipped so it matches what Draw draws on screen.
+// Like Draw, WriteSVG reads the tree directly so it must not be called while the tree is being changed.
+func (q *Quadpix) WriteSVG(w io.Writer, opts ExportOptions) error {
+	opts.defaults()
+	c := q.canvas(opts.Scale)
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		c.width, c.height, c.width, c.height)
+
+	if opts.Background != (pixel.RGBA{}) {
+		fill, opacity := svgColor(opts.Background)
+		fmt.Fprintf(bw, "<rect width=\"%d\" height=\"%d\" fill=\"%s\" fill-opacity=\"%g\"/>\n",
+			c.width, c.height, fill, opacity)
+	}
+
+	for _, s := range q.shapes(opts.DrawOptions) {
+		stroke, opacity := svgColor(s.color)
+		r := c.rect(s.rect)
+		fmt.Fprintf(bw, "<rect x=\"%g\" y=\"%g\" width=\"%g\" height=\"%g\" fill=\"none\" stroke=\"%s\" stroke-opacity=\"%g\" stroke-width=\"%g\"/>\n",
+			r.Min.X, r.Min.Y, r.W(), r.H(), stroke, opacity, s.thickness*c.scale)
+	}
+
+	fmt.Fprint(bw, "</svg>\n")
+
+	return bw.Flush()
+}
+
+// WritePNG writes a picture of the tree's node bounds, entity bounds and any highlighted queries to w as PNG.
+//
+// See WriteSVG for how the picture is laid out and Image for the errors returned before anything is written.
+func (q *Quadpix) WritePNG(w io.Writer, opts ExportOptions) error {
+	img, err := q.Image(opts)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// Image draws the tree's node bounds, entity bounds and any highlighted queries to a new image.
+//
+// See WriteSVG for how the picture is laid out. Image returns an error wrapping ErrImageTooLarge if the
+// tree's bounds at the given scale cover more then MaxImagePixels pixels.
+func (q *Quadpix) Image(opts ExportOptions) (*image.RGBA, error) {
+	opts.defaults()
+
+	// check the size in floating point as it can be too large for an int
+	if pixels := q.rect.W() * opts.Scale * q.rect.H() * opts.Scale; !(pixels <= MaxImagePixels) {
+		return nil, fmt.Errorf("%w: scale %v gives %g pixels, want at most %d", ErrImageTooLarge, opts.Scale, pixels, MaxImagePixels)
+	}
+
+	c := q.canvas(opts.Scale)
+
+	img := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	if opts.Background != (pixel.RGBA{}) {
+		draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+
+	for _, s := range q.shapes(opts.DrawOptions) {
+		r := c.rect(s.rect)
+		bounds := image.Rect(
+			int(math.Floor(r.Min.X)),
+			int(math.Floor(r.Min.Y)),
+			int(math.Ceil(r.Max.X)),
+			int(math.Ceil(r.Max.Y)),
+		)
+
+		// always draw at least one pixel for bounds with no area
+		if bounds.Dx() == 0 {
+			bounds.Max.X++
+		}
+		if bounds.Dy() == 0 {
+			bounds.Max.Y++
+		}
+
+		outline(img, bounds, int(math.Max(1, math.Round(s.thickness*c.scale))), image.NewUniform(s.color))
+	}
+
+	return img, nil
+}
+
+// defaults fills in the default value for any unset option.
+func (o *ExportOptions) defaults() {
+	if o.Scale <= 0 {
+		o.Scale = 1
+	}
+}
+
+// canvas maps the tree's bounds on to an image.
+type canvas struct {
+	root          pixel.Rect
+	scale         float64
+	width, height int
+}
+
+// canvas returns the canvas for the tree at the given scale.
+func (q *Quadpix) canvas(scale float64) canvas {
+	return canvas{
+		root:   q.rect,
+		scale:  scale,
+		width:  int(math.Ceil(q.rect.W() * scale)),
+		height: int(math.Ceil(q.rect.H() * scale)),
+	}
+}
+
+// rect returns the given pixel.Rect in image coordinates with the y axis pointing down.
+func (c canvas) rect(r pixel.Rect) pixel.Rect {
+	return pixel.R(
+		(r.Min.X-c.root.Min.X)*c.scale,
+		(c.root.Max.Y-r.Max.Y)*c.scale,
+		(r.Max.X-c.root.Min.X)*c.scale,
+		(c.root.Max.Y-r.Min.Y)*c.scale,
+	)
+}
+
+// outline draws the outline of the given rectangle with the given line width.
+func outline(img draw.Image, r image.Rectangle, width int, src image.Image) {
+	bands := []image.Rectangle{
+		image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+width),
+		image.Rect(r.Min.X, r.Max.Y-width, r.Max.X, r.Max.Y),
+		image.Rect(r.Min.X, r.Min.Y+width, r.Min.X+width, r.Max.Y-width),
+		image.Rect(r.Max.X-width, r.Min.Y+width, r.Max.X, r.Max.Y-width),
+	}
+
+	for _, band := range bands {
+		band = band.Intersect(r).Intersect(img.Bounds())
+		if !band.Empty() {
+			draw.Draw(img, band, src, image.Point{}, draw.Over)
+		}
+	}
+}
+
+// svgColor returns the SVG colour and opacity of the given pixel.RGBA.
+func svgColor(c pixel.RGBA) (string, float64) {
+	// pixel.RGBA is alpha premultiplied
+	r, g, b := c.R, c.G, c.B
+	if c.A > 0 {
+		r, g, b = r/c.A, g/c.A, b/c.A
+	}
+
+	return fmt.Sprintf("rgb(%d,%d,%d)", channel(r), channel(g), channel(b)), c.A
+}
+
+// channel converts a colour channel from 0 to 1 in to 0 to 255.
+func channel(v float64) int {
+	return int(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
@@ -0,0 +1,114 @@
+package quadpix
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// exportTestTree creates the tree used for the export golden files.
+func exportTestTree() *Quadpix {
+	tree := New(200, 100, 1, 3)
+	tree.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 40, 30)},
+		&Entity{ID: 2, Rect: pixel.R(120, 60, 180, 90)},
+		&Entity{ID: 3, Rect: pixel.R(20, 60, 30, 80)},
+		&Entity{ID: 4, Rect: pixel.R(130, 10, 140, 20)},
+	)
+	return tree
+}
+
+// exportTestOptions are the options used for the export golden files.
+var exportTestOptions = ExportOptions{
+	DrawOptions: DrawOptions{
+		Highlights: []pixel.Rect{pixel.R(100, 50, 150, 75)},
+	},
+	Scale:      2,
+	Background: pixel.RGB(0, 0, 0),
+}
+
+// golden compares got with the golden file of the given name, or updates it with the -update flag.
+func golden(t *testing.T, name string, got []byte) []byte {
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("could not update golden file %v: %v", path, err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read golden file %v: %v", path, err)
+	}
+	return want
+}
+
+func TestQuadpix_WriteSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportTestTree().WriteSVG(&buf, exportTestOptions); err != nil {
+		t.Fatalf("Quadpix.WriteSVG() got error %v", err)
+	}
+
+	if want := golden(t, "export.svg", buf.Bytes()); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Quadpix.WriteSVG() =\n%s\nwant\n%s", buf.Bytes(), want)
+	}
+}
+
+func TestQuadpix_WritePNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportTestTree().WritePNG(&buf, exportTestOptions); err != nil {
+		t.Fatalf("Quadpix.WritePNG() got error %v", err)
+	}
+
+	want := golden(t, "export.png", buf.Bytes())
+
+	// compare the decoded pixels as the encoded bytes can change with the png encoder
+	got, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Quadpix.WritePNG() wrote invalid png %v", err)
+	}
+	wantImg, err := png.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("could not decode golden png %v", err)
+	}
+
+	if got.Bounds() != wantImg.Bounds() {
+		t.Fatalf("Quadpix.WritePNG() bounds = %v, want %v", got.Bounds(), wantImg.Bounds())
+	}
+	if got.Bounds() != image.Rect(0, 0, 400, 200) {
+		t.Errorf("Quadpix.WritePNG() bounds = %v, want scaled tree bounds", got.Bounds())
+	}
+
+	for y := got.Bounds().Min.Y; y < got.Bounds().Max.Y; y++ {
+		for x := got.Bounds().Min.X; x < got.Bounds().Max.X; x++ {
+			if got.At(x, y) != wantImg.At(x, y) {
+				t.Fatalf("Quadpix.WritePNG() pixel %v,%v = %v, want %v", x, y, got.At(x, y), wantImg.At(x, y))
+			}
+		}
+	}
+}
+
+func TestQuadpix_ImageTooLarge(t *testing.T) {
+	tree := exportTestTree()
+	for _, scale := range []float64{1e6, math.Inf(1), math.NaN()} {
+		if _, err := tree.Image(ExportOptions{Scale: scale}); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("Quadpix.Image() at scale %v got error %v, want %v", scale, err, ErrImageTooLarge)
+		}
+
+		var buf bytes.Buffer
+		if err := tree.WritePNG(&buf, ExportOptions{Scale: scale}); !errors.Is(err, ErrImageTooLarge) || buf.Len() != 0 {
+			t.Errorf("Quadpix.WritePNG() at scale %v got error %v and wrote %d bytes", scale, err, buf.Len())
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package quadpix
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// Grid is a uniform spatial hash grid which stores each entity in every cell of a fixed size that it intersects.
+//
+// For worlds full of moving entities of about the same size a Grid is often faster then a quadtree, as
+// finding the cells of an entity is a division rather then a walk down the tree. It works best with a cell
+// size of about the size of the entities, or up to a few times larger.
+//
+// Cells are only stored while they hold entities, so a Grid has no bounds and covers any world.
+// Entities spanning more then maxGridCells cells are kept in a separate list checked by every query.
+//
+// Grid implements SpatialIndex and follows the same rules as Quadpix.
+type Grid struct {
+	cellSize float64
+	overlap  Overlap
+
+	cells map[gridCell]Entities
+	large Entities
+	count int
+}
+
+// gridCell is the position of a cell in a Grid, in cells from the origin.
+type gridCell struct {
+	x, y int64
+}
+
+// maxGridCells is the most cells an entity is stored in before it is kept in the Grid's list of large entities.
+const maxGridCells = 64
+
+// maxGridIndex bounds the cell positions so they can be stored as an int64.
+const maxGridIndex = 1 << 62
+
+var _ SpatialIndex = (*Grid)(nil)
+
+// NewGrid creates a new empty Grid with cells of the given width and height.
+//
+// The Edges and Epsilon Options set how the Grid's queries treat touching entities. All other Options are ignored.
+// NewGrid panics if the cell size is not a finite number greater then 0.
+func NewGrid(cellSize float64, opts ...Option) *Grid {
+	if !(cellSize > 0) || math.IsInf(cellSize, 0) {
+		panic(fmt.Sprintf("quadpix: invalid grid cell size %v", cellSize))
+	}
+
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Grid{
+		cellSize: cellSize,
+		overlap:  cfg.overlap,
+		cells:    make(map[gridCell]Entities),
+	}
+}
+
+// CellSize returns the width and height of the Grid's cells.
+func (g *Grid) CellSize() float64 {
+	return g.cellSize
+}
+
+// Len returns the number of entities in the Grid.
+func (g *Grid) Len() int {
+	return g.count
+}
+
+// Cells returns the number of cells holding at least one entity.
+func (g *Grid) Cells() int {
+	return len(g.cells)
+}
+
+// Insert adds the given pixel.Rect to the Grid as an entity bound with the given Actions.
+func (g *Grid) Insert(rect pixel.Rect, action ...Action) {
+	g.insert(E(rect, action...))
+}
+
+// InsertEntities inserts any number of Entity's in to the Grid.
+//
+// Like Quadpix, entities that have Behaviours but no Actions get their Actions bound from their Behaviours first.
+// InsertEntities returns ErrNoEntitiesGiven if no entities are given, and on error no entities are inserted.
+func (g *Grid) InsertEntities(entities ...*Entity) error {
+	if len(entities) == 0 {
+		return ErrNoEntitiesGiven
+	}
+
+	if err := bindEntities(entities); err != nil {
+		return err
+	}
+
+	for _, e := range entities {
+		g.insert(e)
+	}
+
+	return nil
+}
+
+// Remove the given entity from the Grid.
+//
+// Remove returns ErrNoEntityFound if no entity with the same ID and pixel.Rect is in the Grid.
+func (g *Grid) Remove(entity *Entity) error {
+	return g.remove(entity)
+}
+
+// Update moves the given entity to the new pixel.Rect bounds within the Grid.
+//
+// Update returns ErrNoEntityFound if the entity is not in the Grid. On success the given entity's Rect is set to the new bounds.
+func (g *Grid) Update(entity *Entity, rect pixel.Rect) error {
+	if err := g.remove(entity); err != nil {
+		return err
+	}
+
+	entity.Rect = rect
+	g.insert(entity)
+
+	return nil
+}
+
+// Retrieve gets all entities stored in the cells the given pixel.Rect covers, along with all large entities.
+//
+// Retrieve returns a channel of entities as all Read-Only operations are run on there own thread.
+func (g *Grid) Retrieve(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		out <- g.retrieve(rect)
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersect returns whether or not the given pixel.Rect intersects any entity with in the Grid.
+//
+// Intersect returns a channel of a bool as all Read-Only operations are run on there own thread.
+func (g *Grid) Intersect(rect pixel.Rect) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		out <- g.intersect(rect)
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersects returns a channel of all entities that intersect with the given pixel.Rect within the Grid.
+//
+// Intersects returns a channel of Entities as all Read-Only operations are run on there own thread.
+func (g *Grid) Intersects(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		out <- g.retrieve(rect).IntersectsWith(rect, g.overlap)
+		close(out)
+	}()
+
+	return out
+}
+
+// IsEntity returns whether or not the given entity exists with in the Grid.
+//
+// IsEntity returns a channel holding a bool as all Read-Only operations are run on there own thread.
+// The given entity must have the same ID and pixel.Rect bounds to be found.
+func (g *Grid) IsEntity(entity *Entity) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		out <- g.isEntity(entity)
+		close(out)
+	}()
+
+	return out
+}
+
+// span returns the first and last cell the given pixel.Rect covers and the number of cells between them.
+//
+// ok is false if the pixel.Rect is not valid or is too far from the origin to be stored in cells.
+// With clamp the span is cut to the cells that can be stored instead, so queries reaching past them
+// still find every stored cell they cover.
+func (g *Grid) span(rect pixel.Rect, clamp bool) (min, max gridCell, cells float64, ok bool) {
+	x0, y0 := math.Floor(rect.Min.X/g.cellSize), math.Floor(rect.Min.Y/g.cellSize)
+	x1, y1 := math.Floor(rect.Max.X/g.cellSize), math.Floor(rect.Max.Y/g.cellSize)
+
+	if clamp {
+		x0, y0 = math.Max(x0, -maxGridIndex), math.Max(y0, -maxGridIndex)
+		x1, y1 = math.Min(x1, maxGridIndex), math.Min(y1, maxGridIndex)
+	}
+
+	for _, f := range []float64{x0, y0, x1, y1} {
+		if !(math.Abs(f) < maxGridIndex || clamp && math.Abs(f) == maxGridIndex) {
+			return min, max, 0, false
+		}
+	}
+	if x0 > x1 || y0 > y1 {
+		return min, max, 0, false
+	}
+
+	return gridCell{int64(x0), int64(y0)}, gridCell{int64(x1), int64(y1)}, (x1 - x0 + 1) * (y1 - y0 + 1), true
+}
+
+// reach returns the bounds of the cells a query for the given pixel.Rect has to check.
+//
+// With an inclusive epsilon entities in the cells up to epsilon away from the query bounds can still intersect it.
+func (g *Grid) reach(rect pixel.Rect) pixel.Rect {
+	if e := g.overlap.Epsilon; e > 0 && g.overlap.Edges == EdgesInclusive {
+		return pixel.R(rect.Min.X-e, rect.Min.Y-e, rect.Max.X+e, rect.Max.Y+e)
+	}
+	return rect
+}
+
+// insert stores the given entity in every cell it covers, or in the list of large entities.
+func (g *Grid) insert(entity *Entity) {
+	g.count++
+
+	min, max, cells, ok := g.span(entity.Rect, false)
+	if !ok || cells > maxGridCells {
+		g.large = append(g.large, entity)
+		return
+	}
+
+	for x := min.x; x <= max.x; x++ {
+		for y := min.y; y <= max.y; y++ {
+			c := gridCell{x, y}
+			g.cells[c] = append(g.cells[c], entity)
+		}
+	}
+}
+
+// remove removes the given entity from every cell it covers, dropping cells left empty.
+func (g *Grid) remove(entity *Entity) error {
+	min, max, cells, ok := g.span(entity.Rect, false)
+	if !ok || cells > maxGridCells {
+		large, err := g.large.Remove(entity)
+		if err != nil {
+			return err
+		}
+		g.large = large
+		g.count--
+		return nil
+	}
+
+	// an entity is in all of its cells or none of them, so the first cell decides if it is in the Grid
+	if !g.cells[min].Contains(entity) {
+		return ErrNoEntityFound
+	}
+
+	for x := min.x; x <= max.x; x++ {
+		for y := min.y; y <= max.y; y++ {
+			c := gridCell{x, y}
+			entities, err := g.cells[c].Remove(entity)
+			if err != nil {
+				return err
+			}
+			if len(entities) == 0 {
+				delete(g.cells, c)
+			} else {
+				g.cells[c] = entities
+			}
+		}
+	}
+	g.count--
+
+	return nil
+}
+
+// retrieve gets all entities in the cells the given pixel.Rect reaches without duplicates, along with all large entities.
+func (g *Grid) retrieve(rect pixel.Rect) Entities {
+	entities := append(Entities(nil), g.large...)
+
+	min, max, cells, ok := g.span(g.reach(rect), true)
+	if !ok {
+		return entities
+	}
+
+	// an entity in many cells must only be returned once
+	var seen map[*Entity]struct{}
+	if cells > 1 {
+		seen = make(map[*Entity]struct{})
+	}
+	add := func(found Entities) {
+		for _, e := range found {
+			if seen != nil {
+				if _, ok := seen[e]; ok {
+					continue
+				}
+				seen[e] = struct{}{}
+			}
+			entities = append(entities, e)
+		}
+	}
+
+	// large queries are cheaper to run over the stored cells then over every cell they cover
+	if cells > float64(len(g.cells)) {
+		for c, found := range g.cells {
+			if c.x >= min.x && c.x <= max.x && c.y >= min.y && c.y <= max.y {
+				add(found)
+			}
+		}
+		return entities
+	}
+
+	for x := min.x; x <= max.x; x++ {
+		for y := min.y; y <= max.y; y++ {
+			add(g.cells[gridCell{x, y}])
+		}
+	}
+
+	return entities
+}
+
+// intersect checks if the given pixel.Rect intersects any entity in the Grid.
+func (g *Grid) intersect(rect pixel.Rect) bool {
+	if g.large.IntersectWith(rect, g.overlap) {
+		return true
+	}
+
+	min, max, cells, ok := g.span(g.reach(rect), true)
+	if !ok {
+		return false
+	}
+
+	if cells > float64(len(g.cells)) {
+		for c, found := range g.cells {
+			if c.x >= min.x && c.x <= max.x && c.y >= min.y && c.y <= max.y && found.IntersectWith(rect, g.overlap) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for x := min.x; x <= max.x; x++ {
+		for y := min.y; y <= max.y; y++ {
+			if g.cells[gridCell{x, y}].IntersectWith(rect, g.overlap) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isEntity checks if the given entity is stored in the Grid.
+func (g *Grid) isEntity(entity *Entity) bool {
+	min, _, cells, ok := g.span(entity.Rect, false)
+	if !ok || cells > maxGridCells {
+		return g.large.Contains(entity)
+	}
+	return g.cells[min].Contains(entity)
+}
@@ -0,0 +1,84 @@
+package quadpix
+
+import (
+	"math"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestGrid(t *testing.T) {
+	tests := []struct {
+		name      string
+		rect      pixel.Rect
+		query     pixel.Rect
+		wantCells int
+		wantLarge int
+	}{
+		{"one cell", pixel.R(1, 1, 5, 5), pixel.R(0, 0, 2, 2), 1, 0},
+		{"cell edges", pixel.R(0, 0, 10, 10), pixel.R(10, 10, 10, 10), 4, 0},
+		{"negative", pixel.R(-25, -25, -15, -15), pixel.R(-30, -30, -20, -20), 4, 0},
+		{"far away", pixel.R(1e12, -1e12, 1e12+5, -1e12+5), pixel.R(1e12, -1e12, 1e12+1, -1e12+1), 1, 0},
+		{"large", pixel.R(-1e6, -1e6, 1e6, 1e6), pixel.R(0, 0, 1, 1), 0, 1},
+		{"too far for cells", pixel.R(1e300, 1e300, 1e300, 1e300), pixel.R(1e300, 1e300, 1e300, 1e300), 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGrid(10)
+			e := E(tt.rect)
+			g.InsertEntities(e)
+
+			if got := g.Cells(); got != tt.wantCells {
+				t.Errorf("Grid.Cells() = %v, want %v", got, tt.wantCells)
+			}
+			if got := len(g.large); got != tt.wantLarge {
+				t.Errorf("Grid stored %v large entities, want %v", got, tt.wantLarge)
+			}
+			if got := <-g.Intersects(tt.query); len(got) != 1 || got[0] != e {
+				t.Errorf("Grid.Intersects(%v) = %v, want %v", tt.query, got, e)
+			}
+			if !<-g.IsEntity(e) {
+				t.Errorf("Grid.IsEntity() = false, want true")
+			}
+
+			if err := g.Remove(e); err != nil {
+				t.Fatalf("Grid.Remove() got error %v", err)
+			}
+			if g.Cells() != 0 || len(g.large) != 0 || g.Len() != 0 {
+				t.Errorf("Grid after Remove() holds %v cells, %v large entities and %v entities, want none", g.Cells(), len(g.large), g.Len())
+			}
+		})
+	}
+}
+
+func TestGrid_largeQuery(t *testing.T) {
+	g := NewGrid(1)
+	a, b := E(pixel.R(-500, -500, -499, -499)), E(pixel.R(400, 400, 401, 401))
+	g.InsertEntities(a, b)
+
+	// a query covering far more cells then are stored only checks the stored cells
+	got := <-g.Intersects(pixel.R(-1e9, -1e9, 0, 0))
+	if len(got) != 1 || got[0] != a {
+		t.Errorf("Grid.Intersects() = %v, want %v", got, a)
+	}
+	if !<-g.Intersect(pixel.R(0, 0, 1e9, 1e9)) {
+		t.Errorf("Grid.Intersect() = false, want true")
+	}
+	if got := <-g.Retrieve(pixel.R(-1e9, -1e9, 1e9, 1e9)); len(got) != 2 {
+		t.Errorf("Grid.Retrieve() = %v, want both entities", got)
+	}
+}
+
+func TestNewGrid_panics(t *testing.T) {
+	for _, size := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewGrid(%v) did not panic", size)
+				}
+			}()
+			NewGrid(size)
+		}()
+	}
+}
@@ -0,0 +1,332 @@
+package quadpix
+
+import (
+	"github.com/faiface/pixel"
+)
+
+// record is a single reversible operation done on the tree.
+//
+// from and to are the bounds of the entity before and after the operation.
+type record struct {
+	op     Op
+	entity *Entity
+	from   pixel.Rect
+	to     pixel.Rect
+}
+
+// inverse returns the operation that reverts this operation.
+func (r record) inverse() record {
+	switch r.op {
+	case OpInsert:
+		return record{op: OpRemove, entity: r.entity, from: r.to}
+	case OpRemove:
+		return record{op: OpInsert, entity: r.entity, to: r.from}
+	default:
+		return record{op: r.op, entity: r.entity, from: r.to, to: r.from}
+	}
+}
+
+// undo stages the inverse of this operation in the given transaction.
+func (r record) undo(tx *Tx) {
+	switch r.op {
+	case OpInsert:
+		tx.Remove(r.entity)
+	case OpRemove:
+		r.entity.Rect = r.from
+		tx.InsertEntities(r.entity)
+	case OpUpdate:
+		tx.Update(r.entity, r.from)
+	}
+}
+
+// redo stages this operation again in the given transaction.
+func (r record) redo(tx *Tx) {
+	switch r.op {
+	case OpInsert:
+		r.entity.Rect = r.to
+		tx.InsertEntities(r.entity)
+	case OpRemove:
+		tx.Remove(r.entity)
+	case OpUpdate:
+		tx.Update(r.entity, r.to)
+	}
+}
+
+// step is a named group of operations that are undone and redone together.
+type step struct {
+	name string
+	ops  []record
+}
+
+// bounds returns the current bounds of every entity in the step.
+//
+// Undoing and redoing a step sets the bounds of re-inserted entities while staging, so they are
+// put back with restore if the step can not be applied.
+func (s step) bounds() map[*Entity]pixel.Rect {
+	bounds := make(map[*Entity]pixel.Rect, len(s.ops))
+	for _, r := range s.ops {
+		if _, ok := bounds[r.entity]; !ok {
+			bounds[r.entity] = r.entity.Rect
+		}
+	}
+	return bounds
+}
+
+// restore sets the bounds of each entity back to the given bounds.
+func restore(bounds map[*Entity]pixel.Rect) {
+	for e, rect := range bounds {
+		e.Rect = rect
+	}
+}
+
+// History records the Insert, Remove and Update operations done on a tree so they can be undone and redone.
+//
+// Each operation is its own step unless operations are grouped in to a named step with BeginStep and EndStep.
+// A transaction that is committed is always recorded as a single step.
+//
+// History keeps at most budget operations. When that number is passed the oldest steps are dropped.
+type History struct {
+	tree *Quadpix
+
+	budget int
+	size   int
+
+	undo  []step
+	redo  []step
+	group *step
+
+	paused bool
+}
+
+// EnableHistory starts recording the operations done on the tree and returns the History.
+//
+// budget is the max number of operations kept in the history. If budget is 0 or less the history is unbounded.
+// If history is already enabled its budget is changed and the existing History is returned.
+func (q *Quadpix) EnableHistory(budget int) *History {
+	if q.history == nil {
+		q.history = &History{
+			tree: q,
+		}
+	}
+
+	q.history.budget = budget
+	q.history.trim()
+
+	return q.history
+}
+
+// DisableHistory stops recording operations and drops the current history.
+func (q *Quadpix) DisableHistory() {
+	q.history = nil
+}
+
+// History returns the tree's History or nil if history has not been enabled.
+func (q *Quadpix) History() *History {
+	return q.history
+}
+
+// record counts, logs, marks dirty and sends events for the given operation and adds it to the history if enabled.
+func (q *Quadpix) record(r record) {
+	q.metrics.count(r.op)
+	q.recorder.mutation(r)
+
+	switch r.op {
+	case OpInsert:
+		q.markDirty(r.to)
+		q.notifyEntity(EventInsert, r.entity, r.to)
+	case OpRemove:
+		q.markDirty(r.from)
+		q.notifyEntity(EventRemove, r.entity, r.from)
+	case OpUpdate:
+		q.markDirty(r.from)
+		q.markDirty(r.to)
+
+		// the remove event was sent by Update before the entity was inserted again
+		q.notifyEntity(EventInsert, r.entity, r.to)
+	}
+
+	if q.history == nil || q.history.paused {
+		return
+	}
+
+	q.history.push([]record{r})
+}
+
+// BeginStep starts grouping all following operations in to a single step with the given name.
+//
+// If a step is already open it is ended first.
+func (h *History) BeginStep(name string) {
+	h.EndStep()
+
+	h.group = &step{
+		name: name,
+	}
+}
+
+// EndStep ends the current step started with BeginStep.
+//
+// Empty steps are not recorded.
+func (h *History) EndStep() {
+	if h.group == nil {
+		return
+	}
+
+	group := h.group
+	h.group = nil
+
+	if len(group.ops) > 0 {
+		h.undo = append(h.undo, *group)
+		h.trim()
+	}
+}
+
+// Undo reverts the last step in the history.
+//
+// Any open step is ended first. Undo returns ErrNothingToUndo if there is nothing to undo.
+// If the step can not be reverted the tree is left unchanged and the error is returned.
+func (h *History) Undo() error {
+	h.EndStep()
+
+	if len(h.undo) == 0 {
+		return ErrNothingToUndo
+	}
+
+	s := h.undo[len(h.undo)-1]
+
+	// revert the operations in reverse order
+	bounds := s.bounds()
+	tx := h.tree.Begin()
+	for i := len(s.ops) - 1; i >= 0; i-- {
+		s.ops[i].undo(tx)
+	}
+
+	if err := h.commit(tx); err != nil {
+		restore(bounds)
+		return err
+	}
+
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, s)
+
+	return nil
+}
+
+// Redo applies the last undone step again.
+//
+// Redo returns ErrNothingToRedo if there is nothing to redo.
+// If the step can not be applied the tree is left unchanged and the error is returned.
+func (h *History) Redo() error {
+	h.EndStep()
+
+	if len(h.redo) == 0 {
+		return ErrNothingToRedo
+	}
+
+	s := h.redo[len(h.redo)-1]
+
+	bounds := s.bounds()
+	tx := h.tree.Begin()
+	for i := range s.ops {
+		s.ops[i].redo(tx)
+	}
+
+	if err := h.commit(tx); err != nil {
+		restore(bounds)
+		return err
+	}
+
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, s)
+
+	return nil
+}
+
+// CanUndo returns whether or not there is a step to undo.
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0 || (h.group != nil && len(h.group.ops) > 0)
+}
+
+// CanRedo returns whether or not there is a step to redo.
+func (h *History) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+// UndoName returns the name of the step that would be reverted by Undo.
+func (h *History) UndoName() string {
+	if h.group != nil && len(h.group.ops) > 0 {
+		return h.group.name
+	}
+	if len(h.undo) == 0 {
+		return ""
+	}
+	return h.undo[len(h.undo)-1].name
+}
+
+// RedoName returns the name of the step that would be applied by Redo.
+func (h *History) RedoName() string {
+	if len(h.redo) == 0 {
+		return ""
+	}
+	return h.redo[len(h.redo)-1].name
+}
+
+// Len returns the number of operations held in the history.
+func (h *History) Len() int {
+	return h.size
+}
+
+// Clear drops all recorded steps.
+func (h *History) Clear() {
+	h.undo = nil
+	h.redo = nil
+	h.group = nil
+	h.size = 0
+}
+
+// push adds the given operations to the open step or as a new step.
+//
+// Recording a new operation drops all steps that could be redone.
+func (h *History) push(ops []record) {
+	if len(ops) == 0 {
+		return
+	}
+
+	// new operations invalidate the redo steps
+	for i := range h.redo {
+		h.size -= len(h.redo[i].ops)
+	}
+	h.redo = nil
+
+	h.size += len(ops)
+
+	if h.group != nil {
+		h.group.ops = append(h.group.ops, ops...)
+		return
+	}
+
+	h.undo = append(h.undo, step{ops: ops})
+	h.trim()
+}
+
+// trim drops the oldest steps till the history is within its budget.
+func (h *History) trim() {
+	if h.budget <= 0 {
+		return
+	}
+
+	for h.size > h.budget && len(h.undo) > 0 {
+		h.size -= len(h.undo[0].ops)
+		h.undo[0] = step{}
+		h.undo = h.undo[1:]
+	}
+}
+
+// commit applies the given transaction without recording it.
+func (h *History) commit(tx *Tx) error {
+	h.paused = true
+	defer func() {
+		h.paused = false
+	}()
+
+	return tx.Commit()
+}
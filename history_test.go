@@ -0,0 +1,165 @@
+package quadpix
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestHistory_UndoRedo(t *testing.T) {
+	tree := New(800, 600, 1, 4)
+	history := tree.EnableHistory(0)
+
+	a := &Entity{ID: 1, Rect: pixel.R(0, 0, 50, 50)}
+	b := &Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)}
+
+	if err := tree.InsertEntities(a); err != nil {
+		t.Fatalf("History.Undo() got error on insert %v", err)
+	}
+
+	history.BeginStep("move")
+	if err := tree.InsertEntities(b); err != nil {
+		t.Fatalf("History.Undo() got error on insert %v", err)
+	}
+	if err := tree.Update(a, pixel.R(600, 500, 650, 550)); err != nil {
+		t.Fatalf("History.Undo() got error on update %v", err)
+	}
+	history.EndStep()
+
+	if history.Len() != 3 {
+		t.Errorf("History.Len() = %v, want %v", history.Len(), 3)
+	}
+	if history.UndoName() != "move" {
+		t.Errorf("History.UndoName() = %q, want %q", history.UndoName(), "move")
+	}
+
+	// undo the named step
+	if err := history.Undo(); err != nil {
+		t.Fatalf("History.Undo() got error %v", err)
+	}
+	if a.Rect != pixel.R(0, 0, 50, 50) || !<-tree.IsEntity(a) {
+		t.Errorf("History.Undo() did not move %v back", a)
+	}
+	if <-tree.IsEntity(b) {
+		t.Errorf("History.Undo() did not remove %v", b)
+	}
+
+	// undo the first insert
+	if err := history.Undo(); err != nil {
+		t.Fatalf("History.Undo() got error %v", err)
+	}
+	if <-tree.IsEntity(a) {
+		t.Errorf("History.Undo() did not remove %v", a)
+	}
+	if err := history.Undo(); err != ErrNothingToUndo {
+		t.Errorf("History.Undo() got error %v, want %v", err, ErrNothingToUndo)
+	}
+
+	// redo both steps
+	for history.CanRedo() {
+		if err := history.Redo(); err != nil {
+			t.Fatalf("History.Redo() got error %v", err)
+		}
+	}
+	if a.Rect != pixel.R(600, 500, 650, 550) || !<-tree.IsEntity(a) || !<-tree.IsEntity(b) {
+		t.Errorf("History.Redo() did not restore %v and %v", a, b)
+	}
+	if err := history.Redo(); err != ErrNothingToRedo {
+		t.Errorf("History.Redo() got error %v, want %v", err, ErrNothingToRedo)
+	}
+
+	// a new operation drops the redo steps
+	if err := history.Undo(); err != nil {
+		t.Fatalf("History.Undo() got error %v", err)
+	}
+	tree.Insert(pixel.R(10, 10, 20, 20))
+	if history.CanRedo() {
+		t.Errorf("History.CanRedo() = true after new operation")
+	}
+}
+
+func TestHistory_Budget(t *testing.T) {
+	tree := New(800, 600, 10, 4)
+	history := tree.EnableHistory(2)
+
+	for i := 0; i < 5; i++ {
+		tree.Insert(pixel.R(float64(i), 0, float64(i)+10, 10))
+	}
+
+	if history.Len() != 2 {
+		t.Errorf("History.Len() = %v, want %v", history.Len(), 2)
+	}
+
+	undone := 0
+	for history.CanUndo() {
+		if err := history.Undo(); err != nil {
+			t.Fatalf("History.Undo() got error %v", err)
+		}
+		undone++
+	}
+	if undone != 2 {
+		t.Errorf("History.Undo() undid %v steps, want %v", undone, 2)
+	}
+}
+
+func TestHistory_Tx(t *testing.T) {
+	tree := New(800, 600, 10, 4)
+	history := tree.EnableHistory(0)
+
+	tx := tree.Begin()
+	tx.Insert(pixel.R(0, 0, 10, 10))
+	tx.Insert(pixel.R(20, 20, 30, 30))
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Tx.Commit() got error %v", err)
+	}
+
+	// a failed transaction is not recorded
+	tx = tree.Begin()
+	tx.Insert(pixel.R(40, 40, 50, 50))
+	tx.Remove(E(pixel.R(100, 100, 110, 110)))
+	if err := tx.Commit(); err == nil {
+		t.Fatalf("Tx.Commit() got no error for missing entity")
+	}
+
+	if history.Len() != 2 {
+		t.Errorf("History.Len() = %v, want %v", history.Len(), 2)
+	}
+
+	if err := history.Undo(); err != nil {
+		t.Fatalf("History.Undo() got error %v", err)
+	}
+	if <-tree.Intersect(pixel.R(0, 0, 800, 600)) {
+		t.Errorf("History.Undo() did not undo the whole transaction")
+	}
+}
+
+func TestHistory_UndoFailed(t *testing.T) {
+	tree := New(800, 600, 10, 4)
+	history := tree.EnableHistory(0)
+
+	a := E(pixel.R(0, 0, 10, 10))
+	b := E(pixel.R(20, 20, 30, 30))
+	if err := tree.InsertEntities(b); err != nil {
+		t.Fatal(err)
+	}
+
+	history.BeginStep("swap")
+	if err := tree.InsertEntities(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	history.EndStep()
+
+	// b is reused after being removed and a is removed without being recorded
+	b.Rect = pixel.R(700, 500, 710, 510)
+	tree.node.remove(a)
+
+	if err := history.Undo(); err == nil {
+		t.Fatal("History.Undo() got no error for missing entity")
+	}
+	if b.Rect != pixel.R(700, 500, 710, 510) || <-tree.IsEntity(b) {
+		t.Errorf("History.Undo() changed %v after failing", b)
+	}
+}
@@ -0,0 +1,52 @@
+package quadpix
+
+import (
+	"github.com/faiface/pixel"
+)
+
+// SpatialIndex is the set of operations shared by every structure that can store entities and find them by their bounds.
+//
+// Quadpix implements SpatialIndex, so code written against it can switch between Quadpix and other backends
+// without being rewritten. Every backend must pass the conformance tests in the indextest package.
+//
+// The operations follow the rules of Quadpix:
+//   - Insert and InsertEntities store entities, with InsertEntities returning ErrNoEntitiesGiven if no entities are given.
+//   - Remove and Update return ErrNoEntityFound if no stored entity has the same ID and pixel.Rect as the given entity.
+//   - Update sets the given entity's Rect to the new bounds on success.
+//   - Retrieve returns every entity that may intersect the given pixel.Rect, without duplicates.
+//   - Intersect and Intersects use the Overlap rules the backend was created with.
+//   - IsEntity checks for a stored entity with the same ID and pixel.Rect.
+//
+// Read-Only operations return channels as they may be run on there own thread.
+type SpatialIndex interface {
+	Insert(rect pixel.Rect, action ...Action)
+	InsertEntities(entities ...*Entity) error
+	Remove(entity *Entity) error
+	Update(entity *Entity, rect pixel.Rect) error
+
+	SpatialQuery
+}
+
+// SpatialQuery is the Read-Only part of a SpatialIndex.
+//
+// Static backends that are built once from a list of entities, such as an RTree, only implement SpatialQuery.
+type SpatialQuery interface {
+	Retrieve(rect pixel.Rect) <-chan Entities
+	Intersect(rect pixel.Rect) <-chan bool
+	Intersects(rect pixel.Rect) <-chan Entities
+	IsEntity(entity *Entity) <-chan bool
+}
+
+var _ SpatialIndex = (*Quadpix)(nil)
+
+// bindEntities binds the Actions of every given entity that has Behaviours but no Actions.
+func bindEntities(entities Entities) error {
+	for _, e := range entities {
+		if len(e.Actions) == 0 {
+			if err := e.Bind(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package quadpix_test
+
+import (
+	"testing"
+
+	"github.com/Tskken/quadpix"
+	"github.com/Tskken/quadpix/indextest"
+	"github.com/faiface/pixel"
+)
+
+// newTree returns a Factory for trees using the given Options.
+func newTree(opts ...quadpix.Option) indextest.Factory {
+	return func(bounds pixel.Rect, o quadpix.Overlap) quadpix.SpatialIndex {
+		opts := append([]quadpix.Option{quadpix.Edges(o.Edges), quadpix.Epsilon(o.Epsilon)}, opts...)
+		// indextest.Bounds starts at the origin like every tree made by New
+		return quadpix.New(bounds.W(), bounds.H(), 8, 8, opts...)
+	}
+}
+
+// newGrid returns a Factory for Grids with the given cell size.
+func newGrid(cellSize float64) indextest.Factory {
+	return func(bounds pixel.Rect, o quadpix.Overlap) quadpix.SpatialIndex {
+		return quadpix.NewGrid(cellSize, quadpix.Edges(o.Edges), quadpix.Epsilon(o.Epsilon))
+	}
+}
+
+// newBVH returns a Factory for BVHs with the given margin.
+func newBVH(margin float64) indextest.Factory {
+	return func(bounds pixel.Rect, o quadpix.Overlap) quadpix.SpatialIndex {
+		return quadpix.NewBVH(margin, quadpix.Edges(o.Edges), quadpix.Epsilon(o.Epsilon))
+	}
+}
+
+// newWorld returns a Factory for Worlds with square chunks of the given size and max depth.
+func newWorld(chunkSize float64, maxDepth uint16) indextest.Factory {
+	return func(bounds pixel.Rect, o quadpix.Overlap) quadpix.SpatialIndex {
+		return quadpix.NewWorld(chunkSize, chunkSize, 8, maxDepth, quadpix.Edges(o.Edges), quadpix.Epsilon(o.Epsilon))
+	}
+}
+
+var backends = []struct {
+	name     string
+	newIndex indextest.Factory
+}{
+	{"leaf", newTree()},
+	{"enclosing", newTree(quadpix.Enclosing())},
+	{"loose2", newTree(quadpix.Loose(2))},
+	{"grid16", newGrid(16)},
+	{"grid64", newGrid(64)},
+	{"bvh0", newBVH(0)},
+	{"bvh4", newBVH(4)},
+	// chunk depths giving the same leaf size as the trees above
+	{"world64", newWorld(64, 4)},
+	{"world256", newWorld(256, 6)},
+}
+
+func TestSpatialIndex(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			indextest.Run(t, backend.newIndex)
+		})
+	}
+}
+
+func BenchmarkSpatialIndex(b *testing.B) {
+	for _, backend := range backends {
+		b.Run(backend.name, func(b *testing.B) {
+			indextest.Benchmark(b, backend.newIndex)
+		})
+	}
+}
+
+// build returns a StaticFactory which inserts the entities in to a backend made by newIndex.
+func build(newIndex indextest.Factory) indextest.StaticFactory {
+	return func(entities quadpix.Entities, o quadpix.Overlap) quadpix.SpatialQuery {
+		index := newIndex(indextest.Bounds, o)
+		if len(entities) > 0 {
+			index.InsertEntities(entities...)
+		}
+		return index
+	}
+}
+
+// newRTree is a StaticFactory for RTrees.
+func newRTree(entities quadpix.Entities, o quadpix.Overlap) quadpix.SpatialQuery {
+	return quadpix.NewRTree(entities, quadpix.Edges(o.Edges), quadpix.Epsilon(o.Epsilon))
+}
+
+// newLinear is a StaticFactory for LinearQuadtrees.
+func newLinear(entities quadpix.Entities, o quadpix.Overlap) quadpix.SpatialQuery {
+	return quadpix.NewLinearQuadtree(indextest.Bounds, 8, entities, quadpix.Edges(o.Edges), quadpix.Epsilon(o.Epsilon))
+}
+
+var staticBackends = []struct {
+	name     string
+	newIndex indextest.StaticFactory
+}{
+	{"leaf", build(newTree())},
+	{"loose2", build(newTree(quadpix.Loose(2)))},
+	{"rtree", newRTree},
+	{"linear", newLinear},
+}
+
+func TestSpatialQuery(t *testing.T) {
+	for _, backend := range staticBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			indextest.RunStatic(t, backend.newIndex)
+		})
+	}
+}
+
+func BenchmarkSpatialQuery(b *testing.B) {
+	for _, backend := range staticBackends {
+		b.Run(backend.name, func(b *testing.B) {
+			indextest.BenchmarkStatic(b, backend.newIndex)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package indextest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/Tskken/quadpix"
+	"github.com/faiface/pixel"
+)
+
+// BenchmarkEntities is the number of entities held by the backends used by Benchmark.
+var BenchmarkEntities = 10000
+
+// Benchmark runs the same Insert, Update, Frame, Intersects and Intersect benchmarks against backends created by newIndex,
+// so the results of different backends can be compared directly.
+func Benchmark(b *testing.B, newIndex Factory) {
+	b.Run("Insert", func(b *testing.B) {
+		entities := benchmarkEntities()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			index := newIndex(Bounds, quadpix.Overlap{})
+			index.InsertEntities(entities...)
+		}
+	})
+
+	b.Run("Update", func(b *testing.B) {
+		index, entities := benchmarkIndex(newIndex)
+		r := rand.New(rand.NewSource(2))
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			e := entities[i%len(entities)]
+			rect := e.Rect.Moved(pixel.V(r.Float64()*10-5, r.Float64()*10-5))
+			if rect.Min.X < Bounds.Min.X || rect.Min.Y < Bounds.Min.Y || rect.Max.X > Bounds.Max.X || rect.Max.Y > Bounds.Max.Y {
+				continue
+			}
+			index.Update(e, rect)
+		}
+	})
+
+	b.Run("Frame", func(b *testing.B) {
+		// one frame of a game full of moving sprites, where every sprite moves and then checks what it hits
+		index, entities := benchmarkIndex(newIndex)
+		r := rand.New(rand.NewSource(2))
+		velocity := make([]pixel.Vec, len(entities))
+		for i := range velocity {
+			velocity[i] = pixel.V(r.Float64()*4-2, r.Float64()*4-2)
+		}
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for j, e := range entities {
+				rect := e.Rect.Moved(velocity[j])
+				if rect.Min.X < Bounds.Min.X || rect.Min.Y < Bounds.Min.Y || rect.Max.X > Bounds.Max.X || rect.Max.Y > Bounds.Max.Y {
+					velocity[j] = velocity[j].Scaled(-1)
+					continue
+				}
+				index.Update(e, rect)
+			}
+			for _, e := range entities {
+				<-index.Intersects(e.Rect)
+			}
+		}
+	})
+
+	b.Run("Intersects", func(b *testing.B) {
+		index, _ := benchmarkIndex(newIndex)
+		queries := benchmarkQueries()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			<-index.Intersects(queries[i%len(queries)])
+		}
+	})
+
+	b.Run("Intersect", func(b *testing.B) {
+		index, _ := benchmarkIndex(newIndex)
+		queries := benchmarkQueries()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			<-index.Intersect(queries[i%len(queries)])
+		}
+	})
+}
+
+// benchmarkEntities returns BenchmarkEntities small random entities with in Bounds.
+func benchmarkEntities() quadpix.Entities {
+	r := rand.New(rand.NewSource(1))
+	entities := make(quadpix.Entities, BenchmarkEntities)
+	for i := range entities {
+		entities[i] = &quadpix.Entity{ID: uint64(i + 1), Rect: benchmarkRect(r, 20)}
+	}
+	return entities
+}
+
+// benchmarkIndex returns a backend holding the benchmark entities.
+func benchmarkIndex(newIndex Factory) (quadpix.SpatialIndex, quadpix.Entities) {
+	entities := benchmarkEntities()
+	index := newIndex(Bounds, quadpix.Overlap{})
+	index.InsertEntities(entities...)
+	return index, entities
+}
+
+// benchmarkQueries returns random query bounds with in Bounds.
+func benchmarkQueries() []pixel.Rect {
+	r := rand.New(rand.NewSource(2))
+	queries := make([]pixel.Rect, 1024)
+	for i := range queries {
+		queries[i] = benchmarkRect(r, 100)
+	}
+	return queries
+}
+
+// benchmarkRect returns a random pixel.Rect with in Bounds of up to the given size.
+func benchmarkRect(r *rand.Rand, size float64) pixel.Rect {
+	w, h := r.Float64()*size, r.Float64()*size
+	x := Bounds.Min.X + r.Float64()*(Bounds.W()-w)
+	y := Bounds.Min.Y + r.Float64()*(Bounds.H()-h)
+	return pixel.R(x, y, x+w, y+h)
+}
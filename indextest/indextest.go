@@ -0,0 +1,432 @@
+// Package indextest holds the conformance tests and benchmarks shared by every quadpix.SpatialIndex backend.
+//
+// A backend passes the suite by calling Run from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		indextest.Run(t, func(bounds pixel.Rect, o quadpix.Overlap) quadpix.SpatialIndex {
+//			return NewMyIndex(bounds, o)
+//		})
+//	}
+//
+// Every query result is checked against a brute force search of the entities the backend should hold.
+// Static backends that only implement quadpix.SpatialQuery call RunStatic and BenchmarkStatic instead.
+package indextest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/Tskken/quadpix"
+	"github.com/faiface/pixel"
+)
+
+// Factory creates a new empty backend covering the given bounds which intersects entities under the given Overlap rules.
+type Factory func(bounds pixel.Rect, o quadpix.Overlap) quadpix.SpatialIndex
+
+// Bounds is the area given to every backend created by Run and Benchmark.
+// All entities and queries used by the suite are with in it.
+var Bounds = pixel.R(0, 0, 1024, 1024)
+
+// Overlaps are the Overlap rules every backend is tested with.
+var Overlaps = []quadpix.Overlap{
+	{},
+	{Edges: quadpix.EdgesExclusive},
+	{Epsilon: 2},
+	{Edges: quadpix.EdgesExclusive, Epsilon: 2},
+}
+
+// tile is the grid size most rects are snapped to, so many of them share edges.
+const tile = 16
+
+// Run runs the conformance tests against backends created by newIndex.
+func Run(t *testing.T, newIndex Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s *suite)
+	}{
+		{"Empty", testEmpty},
+		{"Insert", testInsert},
+		{"InsertEntities", testInsertEntities},
+		{"Remove", testRemove},
+		{"Update", testUpdate},
+		{"IsEntity", testIsEntity},
+		{"Points", testPoints},
+		{"Stacked", testStacked},
+		{"Random", testRandom},
+		{"Wide", testWide},
+	}
+
+	for _, o := range Overlaps {
+		o := o
+		t.Run(fmt.Sprintf("%v epsilon %v", o.Edges, o.Epsilon), func(t *testing.T) {
+			for _, tt := range tests {
+				tt := tt
+				t.Run(tt.name, func(t *testing.T) {
+					index := newIndex(Bounds, o)
+					tt.test(t, &suite{
+						index: index,
+						query: index,
+						o:     o,
+						r:     rand.New(rand.NewSource(1)),
+					})
+				})
+			}
+		})
+	}
+}
+
+// suite holds a backend under test and the entities it should hold.
+//
+// query is the backend queried by check, which is the same as index for backends that can be changed.
+type suite struct {
+	index    quadpix.SpatialIndex
+	query    quadpix.SpatialQuery
+	o        quadpix.Overlap
+	r        *rand.Rand
+	entities quadpix.Entities
+	nextID   uint64
+}
+
+// entity returns a new entity with a unique ID and the given bounds.
+func (s *suite) entity(rect pixel.Rect) *quadpix.Entity {
+	s.nextID++
+	return &quadpix.Entity{ID: s.nextID, Rect: rect}
+}
+
+// insert inserts new entities with the given bounds in to the backend.
+func (s *suite) insert(t *testing.T, rects ...pixel.Rect) quadpix.Entities {
+	t.Helper()
+
+	entities := make(quadpix.Entities, len(rects))
+	for i, rect := range rects {
+		entities[i] = s.entity(rect)
+	}
+	if err := s.index.InsertEntities(entities...); err != nil {
+		t.Fatalf("InsertEntities() got error %v", err)
+	}
+	s.entities = append(s.entities, entities...)
+
+	return entities
+}
+
+// remove removes the given entity from the backend.
+func (s *suite) remove(t *testing.T, entity *quadpix.Entity) {
+	t.Helper()
+
+	if err := s.index.Remove(entity); err != nil {
+		t.Fatalf("Remove(%v) got error %v", entity, err)
+	}
+
+	for i := range s.entities {
+		if s.entities[i] == entity {
+			s.entities = append(s.entities[:i], s.entities[i+1:]...)
+			break
+		}
+	}
+}
+
+// update moves the given entity to the given bounds.
+func (s *suite) update(t *testing.T, entity *quadpix.Entity, rect pixel.Rect) {
+	t.Helper()
+
+	if err := s.index.Update(entity, rect); err != nil {
+		t.Fatalf("Update(%v, %v) got error %v", entity, rect, err)
+	}
+	if entity.Rect != rect {
+		t.Fatalf("Update() left entity bounds at %v, want %v", entity.Rect, rect)
+	}
+}
+
+// check runs every query for the given pixel.Rect and compares the results to a brute force search.
+func (s *suite) check(t *testing.T, query pixel.Rect) {
+	t.Helper()
+
+	want := s.entities.IntersectsWith(query, s.o)
+
+	got := <-s.query.Intersects(query)
+	if g, w := ids(got), ids(want); fmt.Sprint(g) != fmt.Sprint(w) {
+		t.Fatalf("Intersects(%v) = %v, want %v", query, g, w)
+	}
+
+	if hit := <-s.query.Intersect(query); hit != (len(want) > 0) {
+		t.Fatalf("Intersect(%v) = %v, want %v", query, hit, len(want) > 0)
+	}
+
+	// Retrieve may return more entities then intersect the query, but only stored ones and each only once
+	retrieved := <-s.query.Retrieve(query)
+	seen := make(map[uint64]bool, len(retrieved))
+	for _, e := range retrieved {
+		if seen[e.ID] {
+			t.Fatalf("Retrieve(%v) returned entity %v more then once", query, e.ID)
+		}
+		seen[e.ID] = true
+
+		if !s.entities.Contains(e) {
+			t.Fatalf("Retrieve(%v) returned entity %v which is not stored", query, e.ID)
+		}
+	}
+	for _, e := range want {
+		if !seen[e.ID] {
+			t.Fatalf("Retrieve(%v) is missing intersecting entity %v", query, e.ID)
+		}
+	}
+}
+
+// checkAll checks a range of random queries, every stored entity's bounds and that every entity is found.
+func (s *suite) checkAll(t *testing.T) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		s.check(t, s.rect(8))
+	}
+	for _, e := range s.entities {
+		s.check(t, e.Rect)
+		if !<-s.query.IsEntity(e) {
+			t.Fatalf("IsEntity(%v) = false, want true", e)
+		}
+	}
+}
+
+// rect returns a random pixel.Rect with in Bounds of up to the given number of tiles wide and high.
+//
+// Most rects are snapped to the tile grid so they share edges with each other, the rest are not.
+func (s *suite) rect(tiles int) pixel.Rect {
+	cols, rows := int(Bounds.W()/tile), int(Bounds.H()/tile)
+
+	if s.r.Intn(4) > 0 {
+		x := Bounds.Min.X + float64(s.r.Intn(cols))*tile
+		y := Bounds.Min.Y + float64(s.r.Intn(rows))*tile
+		w, h := float64(s.r.Intn(tiles+1))*tile, float64(s.r.Intn(tiles+1))*tile
+		return pixel.R(x, y, math.Min(x+w, Bounds.Max.X), math.Min(y+h, Bounds.Max.Y))
+	}
+
+	x := Bounds.Min.X + s.r.Float64()*Bounds.W()
+	y := Bounds.Min.Y + s.r.Float64()*Bounds.H()
+	w, h := s.r.Float64()*float64(tiles)*tile, s.r.Float64()*float64(tiles)*tile
+	return pixel.R(x, y, math.Min(x+w, Bounds.Max.X), math.Min(y+h, Bounds.Max.Y))
+}
+
+// rects returns the given number of random pixel.Rects.
+func (s *suite) rects(n, tiles int) []pixel.Rect {
+	rects := make([]pixel.Rect, n)
+	for i := range rects {
+		rects[i] = s.rect(tiles)
+	}
+	return rects
+}
+
+func testEmpty(t *testing.T, s *suite) {
+	s.check(t, Bounds)
+	s.check(t, pixel.R(10, 10, 20, 20))
+
+	if err := s.index.InsertEntities(); !errors.Is(err, quadpix.ErrNoEntitiesGiven) {
+		t.Errorf("InsertEntities() with no entities got error %v, want %v", err, quadpix.ErrNoEntitiesGiven)
+	}
+
+	missing := s.entity(pixel.R(10, 10, 20, 20))
+	if err := s.index.Remove(missing); !errors.Is(err, quadpix.ErrNoEntityFound) {
+		t.Errorf("Remove() of a missing entity got error %v, want %v", err, quadpix.ErrNoEntityFound)
+	}
+	if err := s.index.Update(missing, pixel.R(30, 30, 40, 40)); !errors.Is(err, quadpix.ErrNoEntityFound) {
+		t.Errorf("Update() of a missing entity got error %v, want %v", err, quadpix.ErrNoEntityFound)
+	}
+	if <-s.index.IsEntity(missing) {
+		t.Errorf("IsEntity() of a missing entity = true, want false")
+	}
+}
+
+func testInsert(t *testing.T, s *suite) {
+	rect := pixel.R(100, 100, 150, 150)
+	called := false
+	s.index.Insert(rect, func() { called = true })
+
+	got := <-s.index.Intersects(rect)
+	if len(got) != 1 || got[0].Rect != rect {
+		t.Fatalf("Intersects(%v) after Insert() = %v, want one entity with bounds %v", rect, got, rect)
+	}
+	if len(got[0].Actions) != 1 {
+		t.Fatalf("Insert() stored %v actions, want 1", len(got[0].Actions))
+	}
+	got[0].Actions[0]()
+	if !called {
+		t.Errorf("Insert() stored a different action")
+	}
+	if !<-s.index.IsEntity(got[0]) {
+		t.Errorf("IsEntity() of an inserted entity = false, want true")
+	}
+}
+
+func testInsertEntities(t *testing.T, s *suite) {
+	s.insert(t, s.rects(500, 4)...)
+	s.checkAll(t)
+
+	// entities covering all of the bounds and its corners
+	s.insert(t, Bounds, pixel.R(0, 0, 0, 0), pixel.R(1024, 1024, 1024, 1024))
+	s.checkAll(t)
+}
+
+func testRemove(t *testing.T, s *suite) {
+	entities := s.insert(t, s.rects(500, 4)...)
+
+	for i, e := range entities {
+		if i%2 == 0 {
+			s.remove(t, e)
+		}
+	}
+	s.checkAll(t)
+
+	// removed entities are gone
+	for i, e := range entities {
+		if i%2 != 0 {
+			continue
+		}
+		if <-s.index.IsEntity(e) {
+			t.Fatalf("IsEntity(%v) of a removed entity = true, want false", e)
+		}
+		if err := s.index.Remove(e); !errors.Is(err, quadpix.ErrNoEntityFound) {
+			t.Fatalf("Remove(%v) of a removed entity got error %v, want %v", e, err, quadpix.ErrNoEntityFound)
+		}
+	}
+
+	// an entity with the right ID but the wrong bounds is not removed
+	kept := entities[1]
+	if err := s.index.Remove(&quadpix.Entity{ID: kept.ID, Rect: kept.Rect.Moved(pixel.V(1, 1))}); err == nil {
+		t.Fatalf("Remove() with the wrong bounds got no error")
+	}
+
+	// remove the rest
+	for _, e := range append(quadpix.Entities{}, s.entities...) {
+		s.remove(t, e)
+	}
+	s.checkAll(t)
+	s.check(t, Bounds)
+}
+
+func testUpdate(t *testing.T, s *suite) {
+	entities := s.insert(t, s.rects(500, 4)...)
+
+	for round := 0; round < 3; round++ {
+		for _, e := range entities {
+			s.update(t, e, s.rect(4))
+		}
+		s.checkAll(t)
+	}
+
+	// small moves across tile edges
+	for _, e := range entities {
+		rect := e.Rect.Moved(pixel.V(float64(s.r.Intn(3)-1)*tile/2, float64(s.r.Intn(3)-1)*tile/2))
+		if rect.Min.X < Bounds.Min.X || rect.Min.Y < Bounds.Min.Y || rect.Max.X > Bounds.Max.X || rect.Max.Y > Bounds.Max.Y {
+			continue
+		}
+		s.update(t, e, rect)
+	}
+	s.checkAll(t)
+
+	// an entity with the right ID but the wrong bounds is not moved
+	e := entities[0]
+	if err := s.index.Update(&quadpix.Entity{ID: e.ID, Rect: e.Rect.Moved(pixel.V(1, 1))}, pixel.R(0, 0, 1, 1)); err == nil {
+		t.Fatalf("Update() with the wrong bounds got no error")
+	}
+	s.checkAll(t)
+}
+
+func testIsEntity(t *testing.T, s *suite) {
+	entities := s.insert(t, s.rects(200, 4)...)
+
+	for _, e := range entities {
+		if !<-s.index.IsEntity(&quadpix.Entity{ID: e.ID, Rect: e.Rect}) {
+			t.Fatalf("IsEntity() of a copy of %v = false, want true", e)
+		}
+		if <-s.index.IsEntity(&quadpix.Entity{ID: e.ID + 1<<32, Rect: e.Rect}) {
+			t.Fatalf("IsEntity() with a different ID = true, want false")
+		}
+		if <-s.index.IsEntity(&quadpix.Entity{ID: e.ID, Rect: e.Rect.Moved(pixel.V(0.5, 0))}) {
+			t.Fatalf("IsEntity() with different bounds = true, want false")
+		}
+	}
+}
+
+func testPoints(t *testing.T, s *suite) {
+	// points on the tile grid sit on the edges of other entities and of any grid the backend uses
+	rects := make([]pixel.Rect, 300)
+	for i := range rects {
+		x, y := float64(s.r.Intn(65))*tile, float64(s.r.Intn(65))*tile
+		rects[i] = pixel.R(x, y, x, y)
+	}
+	s.insert(t, rects...)
+	s.insert(t, s.rects(200, 2)...)
+	s.checkAll(t)
+
+	for i := 0; i < 200; i++ {
+		x, y := float64(s.r.Intn(65))*tile, float64(s.r.Intn(65))*tile
+		s.check(t, pixel.R(x, y, x, y))
+	}
+}
+
+func testStacked(t *testing.T, s *suite) {
+	// many entities with the same bounds can not be split apart
+	rect := pixel.R(500, 500, 516, 516)
+	rects := make([]pixel.Rect, 100)
+	for i := range rects {
+		rects[i] = rect
+	}
+	entities := s.insert(t, rects...)
+	s.checkAll(t)
+
+	for _, e := range entities[:50] {
+		s.remove(t, e)
+	}
+	s.checkAll(t)
+}
+
+func testRandom(t *testing.T, s *suite) {
+	for i := 0; i < 2000; i++ {
+		switch op := s.r.Intn(10); {
+		case op < 5 || len(s.entities) == 0:
+			s.insert(t, s.rect(6))
+		case op < 7:
+			s.remove(t, s.entities[s.r.Intn(len(s.entities))])
+		default:
+			s.update(t, s.entities[s.r.Intn(len(s.entities))], s.rect(6))
+		}
+
+		if i%50 == 0 {
+			s.check(t, s.rect(10))
+		}
+	}
+	s.checkAll(t)
+}
+
+// ids returns the sorted IDs of the given entities.
+func ids(entities quadpix.Entities) []uint64 {
+	out := make([]uint64, len(entities))
+	for i, e := range entities {
+		out[i] = e.ID
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// wideQueries are queries reaching far past Bounds, up to infinite bounds.
+var wideQueries = []pixel.Rect{
+	pixel.R(-1e20, -1e20, 1e20, 1e20),
+	pixel.R(-1e21, 10, 15, 1e21),
+	pixel.R(math.Inf(-1), math.Inf(-1), math.Inf(1), math.Inf(1)),
+}
+
+func testWide(t *testing.T, s *suite) {
+	// queries far larger then any stored entity must still find every entity
+	s.insert(t, pixel.R(10, 10, 20, 20))
+	for _, query := range wideQueries {
+		s.check(t, query)
+	}
+
+	s.insert(t, s.rects(200, 4)...)
+	for _, query := range wideQueries {
+		s.check(t, query)
+	}
+}
@@ -0,0 +1,115 @@
+package indextest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/Tskken/quadpix"
+	"github.com/faiface/pixel"
+)
+
+// StaticFactory builds a new backend holding the given entities which intersects entities under the given Overlap rules.
+type StaticFactory func(entities quadpix.Entities, o quadpix.Overlap) quadpix.SpatialQuery
+
+// RunStatic runs the conformance tests for the Read-Only operations against backends built by newIndex.
+//
+// It is used by static backends that can not be changed once built. Backends implementing all of
+// quadpix.SpatialIndex should use Run.
+func RunStatic(t *testing.T, newIndex StaticFactory) {
+	tests := []struct {
+		name  string
+		rects func(s *suite) []pixel.Rect
+	}{
+		{"Empty", func(s *suite) []pixel.Rect { return nil }},
+		{"One", func(s *suite) []pixel.Rect { return s.rects(1, 4) }},
+		{"Random", func(s *suite) []pixel.Rect { return s.rects(1000, 4) }},
+		{"Large", func(s *suite) []pixel.Rect {
+			return append(s.rects(300, 16), Bounds, pixel.R(0, 0, 0, 0), pixel.R(1024, 1024, 1024, 1024))
+		}},
+		{"Points", func(s *suite) []pixel.Rect {
+			rects := make([]pixel.Rect, 300)
+			for i := range rects {
+				x, y := float64(s.r.Intn(65))*tile, float64(s.r.Intn(65))*tile
+				rects[i] = pixel.R(x, y, x, y)
+			}
+			return rects
+		}},
+		{"Stacked", func(s *suite) []pixel.Rect {
+			rects := make([]pixel.Rect, 100)
+			for i := range rects {
+				rects[i] = pixel.R(500, 500, 516, 516)
+			}
+			return rects
+		}},
+	}
+
+	for _, o := range Overlaps {
+		o := o
+		t.Run(fmt.Sprintf("%v epsilon %v", o.Edges, o.Epsilon), func(t *testing.T) {
+			for _, tt := range tests {
+				tt := tt
+				t.Run(tt.name, func(t *testing.T) {
+					s := &suite{
+						o: o,
+						r: rand.New(rand.NewSource(1)),
+					}
+					for _, rect := range tt.rects(s) {
+						s.entities = append(s.entities, s.entity(rect))
+					}
+
+					input := append(quadpix.Entities(nil), s.entities...)
+					s.query = newIndex(input, o)
+
+					s.checkAll(t)
+					for i := 0; i < 200; i++ {
+						x, y := float64(s.r.Intn(65))*tile, float64(s.r.Intn(65))*tile
+						s.check(t, pixel.R(x, y, x, y))
+					}
+					s.check(t, Bounds)
+					for _, query := range wideQueries {
+						s.check(t, query)
+					}
+
+					for _, e := range s.entities {
+						if <-s.query.IsEntity(&quadpix.Entity{ID: e.ID + 1<<32, Rect: e.Rect}) {
+							t.Fatalf("IsEntity() with a different ID = true, want false")
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkStatic runs the same Build, Intersects and Intersect benchmarks against backends built by newIndex.
+func BenchmarkStatic(b *testing.B, newIndex StaticFactory) {
+	b.Run("Build", func(b *testing.B) {
+		entities := benchmarkEntities()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			newIndex(entities, quadpix.Overlap{})
+		}
+	})
+
+	b.Run("Intersects", func(b *testing.B) {
+		index := newIndex(benchmarkEntities(), quadpix.Overlap{})
+		queries := benchmarkQueries()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			<-index.Intersects(queries[i%len(queries)])
+		}
+	})
+
+	b.Run("Intersect", func(b *testing.B) {
+		index := newIndex(benchmarkEntities(), quadpix.Overlap{})
+		queries := benchmarkQueries()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			<-index.Intersect(queries[i%len(queries)])
+		}
+	})
+}
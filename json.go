@@ -0,0 +1,285 @@
+package quadpix
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// JSON form of a pixel.Vec.
+type vecJSON struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// JSON form of a pixel.Rect.
+type rectJSON struct {
+	Min vecJSON `json:"min"`
+	Max vecJSON `json:"max"`
+}
+
+func toRectJSON(r pixel.Rect) rectJSON {
+	return rectJSON{
+		Min: vecJSON{X: r.Min.X, Y: r.Min.Y},
+		Max: vecJSON{X: r.Max.X, Y: r.Max.Y},
+	}
+}
+
+func (r rectJSON) rect() pixel.Rect {
+	return pixel.R(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+}
+
+// JSON form of a Behaviour.
+type behaviourJSON struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// JSON form of an Entity.
+type entityJSON struct {
+	ID         uint64          `json:"id"`
+	Bounds     rectJSON        `json:"bounds"`
+	Actions    []string        `json:"actions,omitempty"`
+	Behaviours []behaviourJSON `json:"behaviours,omitempty"`
+}
+
+// JSON form of a Quadpix.
+type quadpixJSON struct {
+	Bounds      rectJSON     `json:"bounds"`
+	MaxEntities uint64       `json:"maxEntities"`
+	MaxDepth    uint16       `json:"maxDepth"`
+	Looseness   float64      `json:"looseness,omitempty"`
+	Edges       string       `json:"edges,omitempty"`
+	Epsilon     float64      `json:"epsilon,omitempty"`
+	Entities    []entityJSON `json:"entities"`
+}
+
+// MarshalJSON encodes the tree's root bounds, max entities, max depth, options and all of its entities as JSON.
+//
+// Entities with Behaviours are stored by their Behaviours. For all other entities each Action is stored
+// by the name it was registered with through RegisterAction.
+// MarshalJSON returns ErrUnregisteredAction if any of those Actions has not been registered.
+func (q *Quadpix) MarshalJSON() ([]byte, error) {
+	entities := q.all()
+
+	tree := quadpixJSON{
+		Bounds:      toRectJSON(q.rect),
+		MaxEntities: q.maxEntities,
+		MaxDepth:    q.maxDepth,
+		Looseness:   q.looseness,
+		Epsilon:     q.overlap.Epsilon,
+		Entities:    make([]entityJSON, 0, len(entities)),
+	}
+	if q.overlap.Edges != EdgesInclusive {
+		tree.Edges = q.overlap.Edges.String()
+	}
+
+	for _, e := range entities {
+		entity := entityJSON{
+			ID:     e.ID,
+			Bounds: toRectJSON(e.Rect),
+		}
+
+		if len(e.Behaviours) > 0 {
+			for _, b := range e.Behaviours {
+				entity.Behaviours = append(entity.Behaviours, behaviourJSON{
+					Name:   b.Name,
+					Params: b.Params,
+				})
+			}
+		} else {
+			names, err := actionNames(e)
+			if err != nil {
+				return nil, err
+			}
+			entity.Actions = names
+		}
+
+		tree.Entities = append(tree.Entities, entity)
+	}
+
+	return json.Marshal(tree)
+}
+
+// UnmarshalJSON replaces the tree with the tree encoded in the given JSON.
+//
+// Action names are turned back in to the Actions registered through RegisterAction and Behaviours are bound
+// to the constructors registered through RegisterActionConstructor.
+// UnmarshalJSON returns ErrUnknownAction if a name has not been registered, ErrInvalidBounds
+// if the root or an entity has bounds that can not be stored in the tree and ErrInvalidOption if
+// an option has a value that can not be used. On error the tree is left unchanged.
+func (q *Quadpix) UnmarshalJSON(data []byte) error {
+	var tree quadpixJSON
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return err
+	}
+
+	entities := make(Entities, 0, len(tree.Entities))
+	for _, e := range tree.Entities {
+		actions, err := lookupActions(e.Actions)
+		if err != nil {
+			return err
+		}
+
+		entity := &Entity{
+			ID:      e.ID,
+			Rect:    e.Bounds.rect(),
+			Actions: actions,
+		}
+
+		for _, b := range e.Behaviours {
+			entity.Behaviours = append(entity.Behaviours, Behaviour{
+				Name:   b.Name,
+				Params: b.Params,
+			})
+		}
+		if err := entity.Bind(); err != nil {
+			return err
+		}
+
+		entities = append(entities, entity)
+	}
+
+	cfg := config{
+		maxEntities: tree.MaxEntities,
+		maxDepth:    tree.MaxDepth,
+		looseness:   tree.Looseness,
+		overlap: Overlap{
+			Epsilon: tree.Epsilon,
+		},
+	}
+
+	switch tree.Edges {
+	case "", EdgesInclusive.String():
+	case EdgesExclusive.String():
+		cfg.overlap.Edges = EdgesExclusive
+	default:
+		return fmt.Errorf("%w: edges %q", ErrInvalidOption, tree.Edges)
+	}
+
+	return q.load(tree.Bounds.rect(), cfg, entities, nil)
+}
+
+// loadSplitLimit returns the max number of splits allowed when loading the given number of entities in to a
+// tree without its node structure.
+//
+// Leafs are only split while loading when that separates their entities, so a tree of entities that do not
+// straddle nodes splits at most once per entity at each depth.
+func loadSplitLimit(entities int, maxDepth uint16) int {
+	limit := uint64(entities) * (uint64(maxDepth) + 1)
+	if limit > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(limit)
+}
+
+// load replaces the tree with a new tree of the given configuration holding the given entities.
+//
+// If root is not nil it is used as the already built root node of the tree, otherwise the entities are inserted
+// in to a new root node. The tree is left unchanged if the root or any entity has invalid bounds or the
+// config is invalid.
+func (q *Quadpix) load(rect pixel.Rect, cfg config, entities Entities, root *node) error {
+	// check the root has an area
+	if !validRect(rect) || rect.W() == 0 || rect.H() == 0 {
+		return fmt.Errorf("%w: root %v", ErrInvalidBounds, rect)
+	}
+
+	if !validLooseness(cfg.looseness) {
+		return fmt.Errorf("%w: looseness %v", ErrInvalidOption, cfg.looseness)
+	}
+	if !cfg.overlap.valid() {
+		return fmt.Errorf("%w: edges %v with epsilon %v", ErrInvalidOption, cfg.overlap.Edges, cfg.overlap.Epsilon)
+	}
+
+	// check every entity can be placed in the tree, the same as when it is inserted
+	for _, e := range entities {
+		if !cfg.accepts(rect, e.Rect) {
+			return fmt.Errorf("%w: entity %v", ErrInvalidBounds, e.ID)
+		}
+	}
+
+	q.config = cfg
+
+	if root != nil {
+		q.node = root
+	} else {
+		q.node = q.newRoot(rect, minUint64(cfg.maxEntities, uint64(len(entities))))
+
+		// entities that overlap many nodes can make each insert split every leaf they touch,
+		// so the number of splits is bounded while inserting loaded data.
+		q.splits, q.splitLimit = 0, loadSplitLimit(len(entities), cfg.maxDepth)
+		for _, e := range entities {
+			q.insert(e)
+		}
+		q.splitLimit = 0
+	}
+
+	q.metrics.reset(len(entities))
+	q.recorder.start()
+
+	// the whole of the new tree needs to be redrawn
+	q.dirty = q.dirty[:0]
+	q.markDirty(q.rect)
+
+	// the old history no longer applies to this tree
+	if q.history != nil {
+		q.history.Clear()
+	}
+
+	return nil
+}
+
+// validRect checks that the given pixel.Rect is finite and its min is not greater then its max.
+func validRect(r pixel.Rect) bool {
+	for _, f := range []float64{r.Min.X, r.Min.Y, r.Max.X, r.Max.Y} {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return false
+		}
+	}
+	return r.Min.X <= r.Max.X && r.Min.Y <= r.Max.Y
+}
+
+// actionNames returns the registered names of the given entity's Actions.
+func actionNames(e *Entity) ([]string, error) {
+	if len(e.Actions) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(e.Actions))
+	for _, a := range e.Actions {
+		name, ok := actionName(a)
+		if !ok {
+			return nil, fmt.Errorf("%w: entity %v", ErrUnregisteredAction, e.ID)
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// lookupActions returns the registered Actions for the given names.
+func lookupActions(names []string) ([]Action, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	actions := make([]Action, 0, len(names))
+	for _, name := range names {
+		action, ok := LookupAction(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownAction, name)
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
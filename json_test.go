@@ -0,0 +1,174 @@
+package quadpix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func jsonTestAction() {}
+
+func init() {
+	RegisterAction("json-test", jsonTestAction)
+}
+
+func TestQuadpix_MarshalJSON(t *testing.T) {
+	tree := New(800, 600, 1, 4)
+
+	entities := Entities{
+		&Entity{ID: 1, Rect: pixel.R(0, 0, 50, 50), Actions: []Action{jsonTestAction}},
+		&Entity{ID: 2, Rect: pixel.R(350, 250, 450, 350)},
+		&Entity{ID: 3, Rect: pixel.R(500, 400, 550, 450)},
+	}
+	if err := tree.InsertEntities(entities...); err != nil {
+		t.Fatalf("Quadpix.MarshalJSON() got error on insert %v", err)
+	}
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Quadpix.MarshalJSON() got error %v", err)
+	}
+
+	got := new(Quadpix)
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Quadpix.UnmarshalJSON() got error %v", err)
+	}
+
+	if got.rect != tree.rect || got.maxEntities != tree.maxEntities || got.maxDepth != tree.maxDepth {
+		t.Errorf("Quadpix.UnmarshalJSON() config = %v %v %v, want %v %v %v",
+			got.rect, got.maxEntities, got.maxDepth, tree.rect, tree.maxEntities, tree.maxDepth)
+	}
+
+	all := got.all()
+	if len(all) != len(entities) {
+		t.Errorf("Quadpix.UnmarshalJSON() got %v entities, want %v", len(all), len(entities))
+	}
+	for _, e := range entities {
+		if !<-got.IsEntity(e) {
+			t.Errorf("Quadpix.UnmarshalJSON() entity not found %v", e)
+		}
+	}
+
+	for _, e := range all {
+		if e.ID == 1 {
+			if len(e.Actions) != 1 || reflect.ValueOf(e.Actions[0]).Pointer() != reflect.ValueOf(jsonTestAction).Pointer() {
+				t.Errorf("Quadpix.UnmarshalJSON() did not restore actions for %v", e)
+			}
+		}
+	}
+}
+
+func TestQuadpix_MarshalJSONErrors(t *testing.T) {
+	tree := New(800, 600, 10, 4)
+	tree.Insert(pixel.R(0, 0, 10, 10), func() {})
+
+	if _, err := json.Marshal(tree); !errors.Is(err, ErrUnregisteredAction) {
+		t.Errorf("Quadpix.MarshalJSON() got error %v, want %v", err, ErrUnregisteredAction)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{
+			name:    "unknown action",
+			data:    `{"bounds":{"min":{"x":0,"y":0},"max":{"x":10,"y":10}},"entities":[{"id":1,"bounds":{"min":{"x":0,"y":0},"max":{"x":1,"y":1}},"actions":["missing"]}]}`,
+			wantErr: ErrUnknownAction,
+		},
+		{
+			name:    "empty root",
+			data:    `{"bounds":{"min":{"x":0,"y":0},"max":{"x":0,"y":10}}}`,
+			wantErr: ErrInvalidBounds,
+		},
+		{
+			name:    "entity outside root",
+			data:    `{"bounds":{"min":{"x":0,"y":0},"max":{"x":10,"y":10}},"entities":[{"id":1,"bounds":{"min":{"x":20,"y":20},"max":{"x":30,"y":30}}}]}`,
+			wantErr: ErrInvalidBounds,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := New(800, 600, 10, 4)
+			if err := json.Unmarshal([]byte(tt.data), q); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Quadpix.UnmarshalJSON() got error %v, want %v", err, tt.wantErr)
+			}
+			if q.rect != pixel.R(0, 0, 800, 600) {
+				t.Errorf("Quadpix.UnmarshalJSON() changed tree on error")
+			}
+		})
+	}
+}
+
+func TestQuadpix_UnmarshalJSONSplitLimit(t *testing.T) {
+	// root sized entities in a tree that splits on every insert would split every leaf each time
+	tree := quadpixJSON{
+		Bounds:      toRectJSON(pixel.R(0, 0, 1024, 1024)),
+		MaxEntities: 0,
+		MaxDepth:    math.MaxUint16,
+	}
+	for i := 0; i < 400; i++ {
+		tree.Entities = append(tree.Entities, entityJSON{ID: uint64(i), Bounds: toRectJSON(pixel.R(0, 0, 1024, 1024))})
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := New(800, 600, 10, 4)
+	if err := json.Unmarshal(data, q); err != nil {
+		t.Fatal(err)
+	}
+	// splitting never separates root sized entities
+	if q.splits != 0 {
+		t.Errorf("Quadpix.UnmarshalJSON() split %d times, want 0", q.splits)
+	}
+	if got := len(q.all()); got != 400 {
+		t.Errorf("Quadpix.UnmarshalJSON() loaded %d entities, want 400", got)
+	}
+}
+
+func TestQuadpix_LoadLargeTree(t *testing.T) {
+	tree := New(10000, 10000, 4, 10)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		x, y := r.Float64()*9980, r.Float64()*9980
+		tree.Insert(pixel.R(x, y, x+r.Float64()*20, y+r.Float64()*20))
+	}
+	// duplicate entities are not separated by splitting
+	for i := 0; i < 10; i++ {
+		tree.Insert(pixel.R(5000, 5000, 5001, 5001))
+	}
+	want := <-tree.Stats()
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := new(Quadpix)
+	if err := json.Unmarshal(data, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := tree.WriteBinary(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	fromBinary := new(Quadpix)
+	if err := fromBinary.ReadBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// loading must rebuild the tree, not flatten it in to a few leafs
+	for _, got := range []*Quadpix{fromJSON, fromBinary} {
+		if s := <-got.Stats(); s.Entities != want.Entities || s.Nodes < want.Nodes*9/10 || s.MaxLeafEntities > want.MaxLeafEntities {
+			t.Errorf("loaded tree stats = %+v, want close to %+v", s, want)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package quadpix
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/faiface/pixel"
+)
+
+// LinearQuadtree is a static quadtree stored as one array of entities sorted by the Morton (Z-order) code of
+// the node each entity is in, with no node structs or child pointers.
+//
+// Each entity is placed in the deepest node that fully contains it, like a tree made with the Enclosing Option.
+// Every node covers one range of Morton codes and all entities in a node and its children sit next to each
+// other in the array, so queries walk the implicit tree with binary searches over code ranges rather then
+// by chasing pointers. This makes it a good fit for hundreds of thousands of static points.
+//
+// A LinearQuadtree can not be changed, but it can be rebuilt quickly from a new list of entities with Rebuild.
+// It implements SpatialQuery and follows the same rules as Quadpix.
+type LinearQuadtree struct {
+	overlap  Overlap
+	bounds   pixel.Rect
+	maxDepth uint16
+
+	// items holds the key of every entity sorted by key, with entities holding the entities in the same order.
+	// scratch is the buffer used while sorting, kept for the next Rebuild.
+	items    []linearItem
+	scratch  []linearItem
+	entities Entities
+}
+
+// linearItem is an entity with its key, the Morton code of the first cell of its node at the max depth
+// shifted left by linearDepthBits, with the depth of the node in the low bits.
+type linearItem struct {
+	key    uint64
+	entity *Entity
+}
+
+const (
+	// linearDepthBits is the number of bits of a key holding the depth of a node.
+	linearDepthBits = 5
+	// MaxLinearDepth is the max depth of a LinearQuadtree, the most that fits in the 64 bits of a key.
+	MaxLinearDepth = 29
+)
+
+var _ SpatialQuery = (*LinearQuadtree)(nil)
+
+// NewLinearQuadtree builds a new LinearQuadtree covering the given bounds with nodes down to the given max depth.
+//
+// Entities that are not with in the bounds are kept in the root and checked by every query. The given list is not
+// changed. The Edges and Epsilon Options set how queries treat touching entities and all other Options are ignored.
+// NewLinearQuadtree panics if the bounds have no area or the max depth is more then MaxLinearDepth.
+func NewLinearQuadtree(bounds pixel.Rect, maxDepth uint16, entities Entities, opts ...Option) *LinearQuadtree {
+	if !validRect(bounds) || bounds.W() == 0 || bounds.H() == 0 {
+		panic(fmt.Sprintf("quadpix: invalid linear quadtree bounds %v", bounds))
+	}
+	if maxDepth > MaxLinearDepth {
+		panic(fmt.Sprintf("quadpix: linear quadtree max depth %v is more then %v", maxDepth, MaxLinearDepth))
+	}
+
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	t := &LinearQuadtree{
+		overlap:  cfg.overlap,
+		bounds:   bounds,
+		maxDepth: maxDepth,
+	}
+	t.Rebuild(entities)
+
+	return t
+}
+
+// Rebuild replaces the entities of the LinearQuadtree with the given entities.
+//
+// The arrays of the last build are reused, so rebuilding every frame does not allocate once they are big enough.
+func (t *LinearQuadtree) Rebuild(entities Entities) {
+	t.items = t.items[:0]
+	for _, e := range entities {
+		d, x, y := t.place(e.Rect)
+		t.items = append(t.items, linearItem{key: t.key(d, x, y), entity: e})
+	}
+
+	if cap(t.scratch) < len(t.items) {
+		t.scratch = make([]linearItem, len(t.items))
+	}
+	t.items, t.scratch = radixSort(t.items, t.scratch[:len(t.items)])
+
+	t.entities = t.entities[:0]
+	for _, item := range t.items {
+		t.entities = append(t.entities, item.entity)
+	}
+}
+
+// Len returns the number of entities in the LinearQuadtree.
+func (t *LinearQuadtree) Len() int {
+	return len(t.entities)
+}
+
+// Bounds returns the bounds of the root of the LinearQuadtree.
+func (t *LinearQuadtree) Bounds() pixel.Rect {
+	return t.bounds
+}
+
+// MaxDepth returns the depth of the smallest nodes of the LinearQuadtree.
+func (t *LinearQuadtree) MaxDepth() uint16 {
+	return t.maxDepth
+}
+
+// Retrieve gets all entities in the nodes the given pixel.Rect reaches.
+//
+// Retrieve returns a channel of entities as all Read-Only operations are run on there own thread.
+func (t *LinearQuadtree) Retrieve(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		var entities Entities
+		t.query(rect, func(found Entities) bool {
+			entities = append(entities, found...)
+			return true
+		})
+
+		out <- entities
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersect returns whether or not the given pixel.Rect intersects any entity with in the LinearQuadtree.
+//
+// Intersect returns a channel of a bool as all Read-Only operations are run on there own thread.
+func (t *LinearQuadtree) Intersect(rect pixel.Rect) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		hit := false
+		t.query(rect, func(found Entities) bool {
+			hit = found.IntersectWith(rect, t.overlap)
+			return !hit
+		})
+
+		out <- hit
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersects returns a channel of all entities that intersect with the given pixel.Rect within the LinearQuadtree.
+//
+// Intersects returns a channel of Entities as all Read-Only operations are run on there own thread.
+func (t *LinearQuadtree) Intersects(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		var entities Entities
+		t.query(rect, func(found Entities) bool {
+			entities = append(entities, found.IntersectsWith(rect, t.overlap)...)
+			return true
+		})
+
+		out <- entities
+		close(out)
+	}()
+
+	return out
+}
+
+// IsEntity returns whether or not the given entity exists with in the LinearQuadtree.
+//
+// IsEntity returns a channel holding a bool as all Read-Only operations are run on there own thread.
+// The given entity must have the same ID and pixel.Rect bounds to be found.
+func (t *LinearQuadtree) IsEntity(entity *Entity) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		key := t.key(t.place(entity.Rect))
+		lo := t.search(0, len(t.items), key)
+		hi := t.search(lo, len(t.items), key+1)
+
+		out <- t.entities[lo:hi].Contains(entity)
+		close(out)
+	}()
+
+	return out
+}
+
+// query calls fn with the entities of every node the given pixel.Rect reaches until fn returns false.
+func (t *LinearQuadtree) query(rect pixel.Rect, fn func(entities Entities) bool) {
+	t.visit(rect, t.overlap.placement(), 0, 0, 0, 0, len(t.items), fn)
+}
+
+// visit walks the node at the given depth and position, whose entities and children's entities are held by items lo to hi.
+//
+// It returns false once fn has returned false.
+func (t *LinearQuadtree) visit(rect pixel.Rect, o Overlap, d uint16, x, y uint32, lo, hi int, fn func(entities Entities) bool) bool {
+	if lo == hi {
+		return true
+	}
+
+	cell := t.cell(d, x, y)
+	hit := o.Intersects(cell, rect)
+
+	// the whole node is reached, so every entity in it and its children is with out walking any further
+	if hit && (d == t.maxDepth || encloses(rect, cell)) {
+		return fn(t.entities[lo:hi])
+	}
+
+	// the node's own entities come first as they have the lowest key for its code range.
+	// The root also holds the entities outside of the bounds so it is always checked.
+	own := t.search(lo, hi, t.key(d, x, y)+1)
+	if (hit || d == 0) && own > lo && !fn(t.entities[lo:own]) {
+		return false
+	}
+	if !hit {
+		return true
+	}
+
+	// each child covers the next quarter of the node's code range in Morton order
+	lo = own
+	for i := uint32(0); i < 4; i++ {
+		cx, cy := x<<1|i&1, y<<1|i>>1
+		end := hi
+		if i < 3 {
+			end = t.search(lo, hi, t.key(d+1, x<<1|(i+1)&1, y<<1|(i+1)>>1)&^(1<<linearDepthBits-1))
+		}
+
+		if !t.visit(rect, o, d+1, cx, cy, lo, end, fn) {
+			return false
+		}
+		lo = end
+	}
+
+	return true
+}
+
+// search returns the index of the first item between lo and hi with a key of at least the given key.
+func (t *LinearQuadtree) search(lo, hi int, key uint64) int {
+	return lo + sort.Search(hi-lo, func(i int) bool { return t.items[lo+i].key >= key })
+}
+
+// key returns the key of the node at the given depth and position.
+func (t *LinearQuadtree) key(d uint16, x, y uint32) uint64 {
+	shift := t.maxDepth - d
+	return morton(x<<shift, y<<shift)<<linearDepthBits | uint64(d)
+}
+
+// cell returns the bounds of the node at the given depth and position.
+func (t *LinearQuadtree) cell(d uint16, x, y uint32) pixel.Rect {
+	n := float64(uint64(1) << d)
+	w, h := t.bounds.W()/n, t.bounds.H()/n
+
+	r := pixel.R(
+		t.bounds.Min.X+float64(x)*w, t.bounds.Min.Y+float64(y)*h,
+		t.bounds.Min.X+float64(x+1)*w, t.bounds.Min.Y+float64(y+1)*h,
+	)
+
+	// the last nodes end exactly on the bounds
+	if uint64(x)+1 == uint64(1)<<d {
+		r.Max.X = t.bounds.Max.X
+	}
+	if uint64(y)+1 == uint64(1)<<d {
+		r.Max.Y = t.bounds.Max.Y
+	}
+
+	return r
+}
+
+// place returns the depth and position of the deepest node that fully contains the given pixel.Rect.
+//
+// pixel.Rects that are not with in the bounds are placed in the root.
+func (t *LinearQuadtree) place(rect pixel.Rect) (d uint16, x, y uint32) {
+	if !validRect(rect) || !encloses(t.bounds, rect) {
+		return 0, 0, 0
+	}
+
+	x0, y0 := t.coord(rect.Min.X, t.bounds.Min.X, t.bounds.W()), t.coord(rect.Min.Y, t.bounds.Min.Y, t.bounds.H())
+	x1, y1 := t.coord(rect.Max.X, t.bounds.Min.X, t.bounds.W()), t.coord(rect.Max.Y, t.bounds.Min.Y, t.bounds.H())
+
+	// the corners share the node of the bits there positions have in common
+	shift := uint16(bits.Len32((x0 ^ x1) | (y0 ^ y1)))
+	d, x, y = t.maxDepth-shift, x0>>shift, y0>>shift
+
+	// rounding can put a corner in the wrong cell, so make sure the node holds the pixel.Rect
+	for d > 0 && !encloses(t.cell(d, x, y), rect) {
+		d, x, y = d-1, x>>1, y>>1
+	}
+
+	return d, x, y
+}
+
+// coord returns the position of the cell at the max depth holding the given value.
+func (t *LinearQuadtree) coord(v, min, size float64) uint32 {
+	n := uint64(1) << t.maxDepth
+	c := math.Floor((v - min) / size * float64(n))
+
+	switch {
+	case c < 0:
+		return 0
+	case c >= float64(n):
+		return uint32(n - 1)
+	default:
+		return uint32(c)
+	}
+}
+
+// morton interleaves the bits of x and y, with the bits of x in the even bits of the code.
+func morton(x, y uint32) uint64 {
+	return spread(x) | spread(y)<<1
+}
+
+// spread moves each bit of v to twice its position.
+func spread(v uint32) uint64 {
+	s := uint64(v)
+	s = (s | s<<16) & 0x0000FFFF0000FFFF
+	s = (s | s<<8) & 0x00FF00FF00FF00FF
+	s = (s | s<<4) & 0x0F0F0F0F0F0F0F0F
+	s = (s | s<<2) & 0x3333333333333333
+	s = (s | s<<1) & 0x5555555555555555
+	return s
+}
+
+// radixSort sorts the items by key using scratch as a buffer of the same length.
+//
+// It returns the sorted items and the other buffer, which are the two given slices in some order.
+func radixSort(items, scratch []linearItem) (sorted, buffer []linearItem) {
+	if len(items) == 0 {
+		return items, scratch
+	}
+
+	for shift := uint(0); shift < 64; shift += 8 {
+		var counts [256]int
+		for _, item := range items {
+			counts[byte(item.key>>shift)]++
+		}
+
+		// every key has the same byte so this pass would not move anything
+		if counts[byte(items[0].key>>shift)] == len(items) {
+			continue
+		}
+
+		pos := 0
+		for i, c := range counts {
+			counts[i] = pos
+			pos += c
+		}
+		for _, item := range items {
+			b := byte(item.key >> shift)
+			scratch[counts[b]] = item
+			counts[b]++
+		}
+
+		items, scratch = scratch, items
+	}
+
+	return items, scratch
+}
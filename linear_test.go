@@ -0,0 +1,181 @@
+package quadpix
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestMorton(t *testing.T) {
+	tests := []struct {
+		x, y uint32
+		want uint64
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{0, 1, 2},
+		{1, 1, 3},
+		{2, 0, 4},
+		{3, 3, 15},
+		{math.MaxUint32, 0, 0x5555555555555555},
+		{0, math.MaxUint32, 0xAAAAAAAAAAAAAAAA},
+	}
+
+	for _, tt := range tests {
+		if got := morton(tt.x, tt.y); got != tt.want {
+			t.Errorf("morton(%v, %v) = %#x, want %#x", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestRadixSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 100, 10000} {
+		items := make([]linearItem, n)
+		for i := range items {
+			// few distinct keys to check the sort is stable
+			items[i] = linearItem{key: r.Uint64() % 50 << uint(r.Intn(60)), entity: &Entity{ID: uint64(i)}}
+		}
+		want := append([]linearItem(nil), items...)
+		sort.SliceStable(want, func(i, j int) bool { return want[i].key < want[j].key })
+
+		got, _ := radixSort(items, make([]linearItem, n))
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("radixSort() of %v items differs at %v, got %v, want %v", n, i, got[i].key, want[i].key)
+			}
+		}
+	}
+}
+
+func TestLinearQuadtree_place(t *testing.T) {
+	tree := NewLinearQuadtree(pixel.R(-100, -100, 100, 100), 6, nil)
+
+	tests := []struct {
+		name      string
+		rect      pixel.Rect
+		wantDepth uint16
+	}{
+		{"point", pixel.R(1, 1, 1, 1), 6},
+		{"crosses the center", pixel.R(-1, -1, 1, 1), 0},
+		{"in a quadrant", pixel.R(10, 10, 20, 20), 3},
+		{"on the max edge", pixel.R(100, 100, 100, 100), 6},
+		{"outside", pixel.R(150, 150, 160, 160), 0},
+		{"partly outside", pixel.R(90, 90, 110, 110), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, x, y := tree.place(tt.rect)
+			if d != tt.wantDepth {
+				t.Errorf("LinearQuadtree.place(%v) depth = %v, want %v", tt.rect, d, tt.wantDepth)
+			}
+
+			cell := tree.cell(d, x, y)
+			if d > 0 && !encloses(cell, tt.rect) {
+				t.Errorf("LinearQuadtree.place(%v) gave node %v which does not hold it", tt.rect, cell)
+			}
+		})
+	}
+}
+
+func TestLinearQuadtree_Rebuild(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	entities := make(Entities, 5000)
+	for i := range entities {
+		entities[i] = &Entity{ID: uint64(i + 1), Rect: randomRect(r, 20)}
+	}
+
+	tree := NewLinearQuadtree(pixel.R(0, 0, 1000, 1000), 10, entities)
+
+	// the keys are sorted and match where each entity is placed
+	for i, item := range tree.items {
+		if i > 0 && tree.items[i-1].key > item.key {
+			t.Fatalf("item %v is out of order", i)
+		}
+		if item.key != tree.key(tree.place(item.entity.Rect)) || tree.entities[i] != item.entity {
+			t.Fatalf("item %v has the wrong key or entity", i)
+		}
+	}
+
+	// moved entities are found after a rebuild
+	for _, e := range entities {
+		e.Rect = e.Rect.Moved(pixel.V(r.Float64()*10, r.Float64()*10))
+	}
+	tree.Rebuild(entities)
+	for _, e := range entities {
+		if !<-tree.IsEntity(e) {
+			t.Fatalf("LinearQuadtree.IsEntity(%v) after Rebuild() = false, want true", e)
+		}
+	}
+
+	if allocs := testing.AllocsPerRun(10, func() { tree.Rebuild(entities) }); allocs > 0 {
+		t.Errorf("LinearQuadtree.Rebuild() made %v allocations, want 0", allocs)
+	}
+}
+
+func TestNewLinearQuadtree_panics(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   pixel.Rect
+		maxDepth uint16
+	}{
+		{"no area", pixel.R(0, 0, 0, 10), 4},
+		{"not finite", pixel.R(0, 0, math.Inf(1), 10), 4},
+		{"too deep", pixel.R(0, 0, 10, 10), MaxLinearDepth + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewLinearQuadtree() did not panic")
+				}
+			}()
+			NewLinearQuadtree(tt.bounds, tt.maxDepth, nil)
+		})
+	}
+}
+
+// benchmarkPoints returns the given number of random points with in a 1000 by 1000 area.
+func benchmarkPoints(n int) Entities {
+	r := rand.New(rand.NewSource(1))
+	entities := make(Entities, n)
+	for i := range entities {
+		x, y := r.Float64()*1000, r.Float64()*1000
+		entities[i] = &Entity{ID: uint64(i + 1), Rect: pixel.R(x, y, x, y)}
+	}
+	return entities
+}
+
+func BenchmarkLinearQuadtree_Rebuild(b *testing.B) {
+	entities := benchmarkPoints(200000)
+	tree := NewLinearQuadtree(pixel.R(0, 0, 1000, 1000), 16, entities)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Rebuild(entities)
+	}
+}
+
+func BenchmarkLinearQuadtree_Intersects(b *testing.B) {
+	entities := benchmarkPoints(200000)
+	tree := NewLinearQuadtree(pixel.R(0, 0, 1000, 1000), 16, entities)
+	r := rand.New(rand.NewSource(2))
+	queries := make([]pixel.Rect, 1024)
+	for i := range queries {
+		queries[i] = randomRect(r, 20)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		q := queries[i%len(queries)]
+		tree.query(q, func(found Entities) bool {
+			found.IntersectsWith(q, tree.overlap)
+			return true
+		})
+	}
+}
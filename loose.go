@@ -0,0 +1,182 @@
+package quadpix
+
+import (
+	"github.com/faiface/pixel"
+)
+
+// loose returns the bounds of this node grown by the looseness of the tree.
+func (n *node) loose() pixel.Rect {
+	f := n.tree.looseness
+	if f <= 1 {
+		return n.rect
+	}
+
+	c := n.rect.Center()
+	w, h := n.rect.W()*f/2, n.rect.H()*f/2
+	return pixel.R(c.X-w, c.Y-h, c.X+w, c.Y+h)
+}
+
+// holds checks if this node can store an entity with the given pixel.Rect bounds.
+//
+// Nodes of a loose tree hold entities that fit in their loose bounds, apart from the root which also holds
+// every entity that does not fit anywhere else. Other nodes hold the entities they intersect.
+func (n *node) holds(rect pixel.Rect) bool {
+	if n.tree.looseness > 0 {
+		return n.depth == 0 || encloses(n.loose(), rect)
+	}
+	return n.tree.overlap.placement().Intersects(n.rect, rect)
+}
+
+// fit returns the child of this node whose loose bounds fully contain the given pixel.Rect,
+// or nil if the pixel.Rect does not fit in any child.
+//
+// Only the child holding the center of the pixel.Rect is checked as the loose bounds of a child
+// can only contain pixel.Rects centered with in its own bounds.
+func (n *node) fit(rect pixel.Rect) *node {
+	if len(n.children) == 0 {
+		return nil
+	}
+
+	c, e := n.rect.Center(), rect.Center()
+
+	i := 0
+	if e.X >= c.X {
+		i |= 1
+	}
+	if e.Y >= c.Y {
+		i |= 2
+	}
+
+	child := n.children[i]
+	if !encloses(child.loose(), rect) {
+		return nil
+	}
+	return child
+}
+
+// home returns the node the given pixel.Rect is stored in within a loose tree.
+func (n *node) home(rect pixel.Rect) *node {
+	for {
+		child := n.fit(rect)
+		if child == nil {
+			return n
+		}
+		n = child
+	}
+}
+
+// insertLoose inserts the given entity in to the deepest node it fits in.
+func (n *node) insertLoose(entity *Entity) {
+	// pass the entity down to the child it fits in
+	if len(n.children) > 0 {
+		if child := n.fit(entity.Rect); child != nil {
+			child.insertLoose(entity)
+			return
+		}
+
+		// the entity does not fit in any child so it stays in this branch
+		n.entities = append(n.entities, entity)
+		return
+	}
+
+	// check for a needed split
+	if uint64(len(n.entities)+1) > n.tree.maxEntities && n.depth < n.tree.maxDepth && n.tree.canSplit() {
+		n.split()
+
+		// move each entity that fits in a child down, the rest stay in this node
+		moved := append(n.entities, entity)
+		n.entities = nil
+		for _, e := range moved {
+			if child := n.fit(e.Rect); child != nil {
+				child.entities = append(child.entities, e)
+			} else {
+				n.entities = append(n.entities, e)
+			}
+		}
+
+		n.tree.notify(Event{Type: EventSplit, Nodes: []pixel.Rect{n.rect}, Entities: moved})
+		return
+	}
+
+	n.entities = append(n.entities, entity)
+}
+
+// removeLoose removes the given entity from the node it is stored in.
+func (n *node) removeLoose(entity *Entity) error {
+	if child := n.fit(entity.Rect); child != nil {
+		if err := child.removeLoose(entity); err != nil {
+			return err
+		}
+
+		// attempted a collapse
+		// does nothing of not needed
+		n.collapse()
+
+		return nil
+	}
+
+	entities, err := n.entities.Remove(entity)
+	if err != nil {
+		return err
+	}
+	n.entities = entities
+
+	return nil
+}
+
+// retrieveLoose gets all entities from every node whose loose bounds the given pixel.Rect intersects.
+func (n *node) retrieveLoose(rect pixel.Rect, entities Entities, m *Metrics) Entities {
+	m.visit()
+
+	entities = append(entities, n.entities...)
+
+	for _, child := range n.reach(rect) {
+		entities = child.retrieveLoose(rect, entities, m)
+	}
+
+	return entities
+}
+
+// intersectLoose checks if the given pixel.Rect intersects any entity with in this node or its children.
+func (n *node) intersectLoose(rect pixel.Rect, m *Metrics) bool {
+	m.visit()
+
+	if n.entities.IntersectWith(rect, n.tree.overlap) {
+		return true
+	}
+
+	for _, child := range n.reach(rect) {
+		if child.intersectLoose(rect, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEntityLoose checks if the given entity is stored in the node it belongs in.
+func (n *node) isEntityLoose(entity *Entity, m *Metrics) bool {
+	m.visit()
+
+	if child := n.fit(entity.Rect); child != nil {
+		return child.isEntityLoose(entity, m)
+	}
+
+	return n.entities.Contains(entity)
+}
+
+// reach finds all children whose loose bounds the given pixel.Rect intersects.
+func (n *node) reach(rect pixel.Rect) (nodes []*node) {
+	for i := range n.children {
+		if n.tree.overlap.placement().Intersects(n.children[i].loose(), rect) {
+			nodes = append(nodes, n.children[i])
+		}
+	}
+	return
+}
+
+// encloses checks if the outer pixel.Rect fully contains the inner pixel.Rect.
+func encloses(outer, inner pixel.Rect) bool {
+	return outer.Min.X <= inner.Min.X && outer.Min.Y <= inner.Min.Y &&
+		inner.Max.X <= outer.Max.X && inner.Max.Y <= outer.Max.Y
+}
@@ -0,0 +1,249 @@
+package quadpix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+// randomRect returns a random pixel.Rect with in a 1000 by 1000 root of at most the given size.
+func randomRect(r *rand.Rand, size float64) pixel.Rect {
+	w, h := r.Float64()*size, r.Float64()*size
+	x, y := r.Float64()*(1000-w), r.Float64()*(1000-h)
+	return pixel.R(x, y, x+w, y+h)
+}
+
+// ids returns the sorted IDs of the given entities.
+func ids(entities Entities) []uint64 {
+	out := make([]uint64, len(entities))
+	for i, e := range entities {
+		out[i] = e.ID
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func TestLoose(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "leaf placement", opts: nil},
+		{name: "enclosing", opts: []Option{Enclosing()}},
+		{name: "loose 1.5", opts: []Option{Loose(1.5)}},
+		{name: "loose 2", opts: []Option{Loose(2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(7))
+			tree := New(1000, 1000, 4, 6, tt.opts...)
+
+			entities := make(Entities, 300)
+			for i := range entities {
+				entities[i] = &Entity{ID: uint64(i + 1), Rect: randomRect(r, 120)}
+			}
+			tree.InsertEntities(entities...)
+
+			// move and remove some entities so nodes split and collapse
+			for i := 0; i < 200; i++ {
+				e := entities[r.Intn(len(entities))]
+				if err := tree.Update(e, randomRect(r, 120)); err != nil {
+					t.Fatalf("Quadpix.Update() got error %v", err)
+				}
+			}
+			for _, e := range entities[:150] {
+				if err := tree.Remove(e); err != nil {
+					t.Fatalf("Quadpix.Remove() got error %v", err)
+				}
+			}
+			entities = entities[150:]
+
+			if err := tree.validate(); err != nil {
+				t.Fatalf("Quadpix.validate() got error %v", err)
+			}
+
+			for i := 0; i < 100; i++ {
+				query := randomRect(r, 300)
+
+				got := ids(<-tree.Intersects(query))
+				want := ids(entities.Intersects(query))
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("Quadpix.Intersects(%v) = %v, want %v", query, got, want)
+				}
+				if hit := <-tree.Intersect(query); hit != (len(want) > 0) {
+					t.Fatalf("Quadpix.Intersect(%v) = %v, want %v", query, hit, len(want) > 0)
+				}
+			}
+
+			for _, e := range entities {
+				if !<-tree.IsEntity(e) {
+					t.Fatalf("Quadpix.IsEntity(%v) = false, want true", e)
+				}
+			}
+
+			stats := tree.stats()
+			if tree.looseness > 0 && stats.Duplication() != 1 {
+				t.Errorf("loose tree Stats().Duplication() = %v, want 1", stats.Duplication())
+			}
+		})
+	}
+}
+
+func TestLoose_roundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	tree := New(1000, 1000, 4, 6, Loose(2))
+	for i := 0; i < 100; i++ {
+		tree.InsertEntities(&Entity{ID: uint64(i + 1), Rect: randomRect(r, 100)})
+	}
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := new(Quadpix)
+	if err := json.Unmarshal(data, fromJSON); err != nil {
+		t.Fatalf("json.Unmarshal() got error %v", err)
+	}
+
+	data, err = tree.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBinary := new(Quadpix)
+	if err := fromBinary.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Quadpix.UnmarshalBinary() got error %v", err)
+	}
+
+	for _, got := range []*Quadpix{fromJSON, fromBinary} {
+		if got.looseness != 2 {
+			t.Errorf("loaded looseness = %v, want %v", got.looseness, 2)
+		}
+		if err := got.validate(); err != nil {
+			t.Errorf("loaded tree is invalid: %v", err)
+		}
+		if len(got.all()) != len(tree.all()) {
+			t.Errorf("loaded tree has %v entities, want %v", len(got.all()), len(tree.all()))
+		}
+	}
+
+	// the binary snapshot keeps the node structure
+	if fromBinary.stats() != tree.stats() {
+		t.Errorf("Quadpix.UnmarshalBinary() stats = %+v, want %+v", fromBinary.stats(), tree.stats())
+	}
+
+	// an entity stored twice is not a valid loose tree
+	tree.children[0].entities = append(tree.children[0].entities, tree.children[1].all()[0])
+	if err := tree.validate(); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("Quadpix.validate() of a duplicated entity got error %v, want %v", err, ErrInvalidTree)
+	}
+
+	bad := []byte(`{"bounds":{"min":{"x":0,"y":0},"max":{"x":10,"y":10}},"maxEntities":2,"maxDepth":2,"looseness":0.5}`)
+	if err := json.Unmarshal(bad, new(Quadpix)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("json.Unmarshal() with looseness 0.5 got error %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestLoose_panics(t *testing.T) {
+	for _, factor := range []float64{0, 0.5, -1, math.Inf(1), math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Loose(%v) did not panic", factor)
+				}
+			}()
+			Loose(factor)
+		}()
+	}
+}
+
+// benchmark trees of 10000 entities of mixed sizes in each placement mode.
+var benchmarkModes = []struct {
+	name string
+	opts []Option
+}{
+	{"leaf", nil},
+	{"enclosing", []Option{Enclosing()}},
+	{"loose1.5", []Option{Loose(1.5)}},
+	{"loose2", []Option{Loose(2)}},
+}
+
+func benchmarkTree(opts []Option) (*Quadpix, Entities) {
+	r := rand.New(rand.NewSource(1))
+	tree := New(1000, 1000, 8, 8, opts...)
+
+	entities := make(Entities, 10000)
+	for i := range entities {
+		size := 10.0
+		// one in ten entities is large
+		if i%10 == 0 {
+			size = 150
+		}
+		entities[i] = &Entity{ID: uint64(i + 1), Rect: randomRect(r, size)}
+	}
+	tree.InsertEntities(entities...)
+
+	return tree, entities
+}
+
+func BenchmarkLoose_Insert(b *testing.B) {
+	for _, mode := range benchmarkModes {
+		b.Run(mode.name, func(b *testing.B) {
+			_, entities := benchmarkTree(mode.opts)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				tree := New(1000, 1000, 8, 8, mode.opts...)
+				tree.InsertEntities(entities...)
+
+				if i == 0 {
+					b.ReportMetric(tree.stats().Duplication(), "copies/entity")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLoose_Intersects(b *testing.B) {
+	for _, mode := range benchmarkModes {
+		b.Run(mode.name, func(b *testing.B) {
+			tree, _ := benchmarkTree(mode.opts)
+			r := rand.New(rand.NewSource(2))
+			queries := make([]pixel.Rect, 1024)
+			for i := range queries {
+				queries[i] = randomRect(r, 100)
+			}
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				q := queries[i%len(queries)]
+				tree.retrieve(q, nil).Intersects(q)
+			}
+		})
+	}
+}
+
+func BenchmarkLoose_Update(b *testing.B) {
+	for _, mode := range benchmarkModes {
+		b.Run(mode.name, func(b *testing.B) {
+			tree, entities := benchmarkTree(mode.opts)
+			r := rand.New(rand.NewSource(2))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				e := entities[i%len(entities)]
+				rect := e.Rect.Moved(pixel.V(r.Float64()*10-5, r.Float64()*10-5))
+				if rect.Min.X < 0 || rect.Min.Y < 0 || rect.Max.X > 1000 || rect.Max.Y > 1000 {
+					continue
+				}
+				tree.Update(e, rect)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package quadpix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+)
+
+// Metrics counts the operations done on a tree and what they cost.
+//
+// All counters are updated atomically so a Metrics can be read with Snapshot or WritePrometheus
+// while the tree is in use, including from the threads running read operations.
+type Metrics struct {
+	// 64 bit values are kept first so they stay aligned for atomic use on 32 bit platforms.
+	inserts          uint64
+	removes          uint64
+	updates          uint64
+	retrieves        uint64
+	intersects       uint64
+	intersectQueries uint64
+	isEntities       uint64
+	nodesVisited     uint64
+	entitiesFound    uint64
+	splits           uint64
+	collapses        uint64
+	entities         int64
+}
+
+// MetricsSnapshot is the value of every counter of a Metrics at one point in time.
+type MetricsSnapshot struct {
+	// Number of entities inserted, removed and updated.
+	Inserts, Removes, Updates uint64
+	// Number of Retrieve, Intersect, Intersects and IsEntity calls.
+	Retrieves, Intersects, IntersectQueries, IsEntities uint64
+	// Number of nodes visited and entities returned by all queries.
+	//
+	// Only queries made through the tree's methods are counted, not the work done by Explain,
+	// Draw, DebugHandler or the exporters.
+	NodesVisited, EntitiesFound uint64
+	// Number of node splits and collapses.
+	Splits, Collapses uint64
+	// Number of entities currently in the tree.
+	Entities int64
+}
+
+// EnableMetrics starts counting the operations done on the tree and returns the Metrics.
+//
+// If metrics are already enabled the existing Metrics is returned.
+// Metrics should be enabled before the tree is shared with other threads.
+func (q *Quadpix) EnableMetrics() *Metrics {
+	if q.metrics == nil {
+		q.metrics = &Metrics{
+			entities: int64(len(q.all())),
+		}
+	}
+
+	return q.metrics
+}
+
+// DisableMetrics stops counting operations and drops the current Metrics.
+func (q *Quadpix) DisableMetrics() {
+	q.metrics = nil
+}
+
+// Metrics returns the tree's Metrics or nil if metrics have not been enabled.
+func (q *Quadpix) Metrics() *Metrics {
+	return q.metrics
+}
+
+// Snapshot returns the current value of every counter.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		Inserts:          atomic.LoadUint64(&m.inserts),
+		Removes:          atomic.LoadUint64(&m.removes),
+		Updates:          atomic.LoadUint64(&m.updates),
+		Retrieves:        atomic.LoadUint64(&m.retrieves),
+		Intersects:       atomic.LoadUint64(&m.intersects),
+		IntersectQueries: atomic.LoadUint64(&m.intersectQueries),
+		IsEntities:       atomic.LoadUint64(&m.isEntities),
+		NodesVisited:     atomic.LoadUint64(&m.nodesVisited),
+		EntitiesFound:    atomic.LoadUint64(&m.entitiesFound),
+		Splits:           atomic.LoadUint64(&m.splits),
+		Collapses:        atomic.LoadUint64(&m.collapses),
+		Entities:         atomic.LoadInt64(&m.entities),
+	}
+}
+
+// WritePrometheus writes the current counters to w in the Prometheus text exposition format.
+//
+// If tree is not empty every sample is given a tree label with that value so the metrics of
+// several trees can be served together.
+func (m *Metrics) WritePrometheus(w io.Writer, tree string) error {
+	s := m.Snapshot()
+	bw := bufio.NewWriter(w)
+
+	labels := func(pairs ...string) string {
+		if tree != "" {
+			pairs = append([]string{"tree", tree}, pairs...)
+		}
+		if len(pairs) == 0 {
+			return ""
+		}
+
+		out := "{"
+		for i := 0; i < len(pairs); i += 2 {
+			if i > 0 {
+				out += ","
+			}
+			out += pairs[i] + "=" + strconv.Quote(pairs[i+1])
+		}
+		return out + "}"
+	}
+
+	metric := func(name, kind, help string, samples ...string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for i := 0; i < len(samples); i += 2 {
+			fmt.Fprintf(bw, "%s%s %s\n", name, samples[i], samples[i+1])
+		}
+	}
+
+	u := func(v uint64) string {
+		return strconv.FormatUint(v, 10)
+	}
+
+	metric("quadpix_operations_total", "counter", "Number of operations run on the tree.",
+		labels("op", "insert"), u(s.Inserts),
+		labels("op", "remove"), u(s.Removes),
+		labels("op", "update"), u(s.Updates),
+		labels("op", "retrieve"), u(s.Retrieves),
+		labels("op", "intersect"), u(s.Intersects),
+		labels("op", "intersects"), u(s.IntersectQueries),
+		labels("op", "is_entity"), u(s.IsEntities),
+	)
+	metric("quadpix_query_nodes_visited_total", "counter", "Number of nodes visited by queries.",
+		labels(), u(s.NodesVisited))
+	metric("quadpix_query_entities_total", "counter", "Number of entities returned by queries.",
+		labels(), u(s.EntitiesFound))
+	metric("quadpix_splits_total", "counter", "Number of nodes split.",
+		labels(), u(s.Splits))
+	metric("quadpix_collapses_total", "counter", "Number of nodes collapsed.",
+		labels(), u(s.Collapses))
+	metric("quadpix_entities", "gauge", "Number of entities in the tree.",
+		labels(), strconv.FormatInt(s.Entities, 10))
+
+	return bw.Flush()
+}
+
+// count adds one to the counter of the given operation.
+func (m *Metrics) count(op Op) {
+	if m == nil {
+		return
+	}
+
+	switch op {
+	case OpInsert:
+		atomic.AddUint64(&m.inserts, 1)
+		atomic.AddInt64(&m.entities, 1)
+	case OpRemove:
+		atomic.AddUint64(&m.removes, 1)
+		atomic.AddInt64(&m.entities, -1)
+	case OpUpdate:
+		atomic.AddUint64(&m.updates, 1)
+	}
+}
+
+// retrieve counts a Retrieve query and the number of entities it returned.
+func (m *Metrics) retrieve(found int) {
+	if m != nil {
+		atomic.AddUint64(&m.retrieves, 1)
+		atomic.AddUint64(&m.entitiesFound, uint64(found))
+	}
+}
+
+// intersectQuery counts an Intersects query and the number of entities it returned.
+func (m *Metrics) intersectQuery(found int) {
+	if m != nil {
+		atomic.AddUint64(&m.intersectQueries, 1)
+		atomic.AddUint64(&m.entitiesFound, uint64(found))
+	}
+}
+
+// intersect counts an Intersect query.
+func (m *Metrics) intersect() {
+	if m != nil {
+		atomic.AddUint64(&m.intersects, 1)
+	}
+}
+
+// isEntity counts an IsEntity query.
+func (m *Metrics) isEntity() {
+	if m != nil {
+		atomic.AddUint64(&m.isEntities, 1)
+	}
+}
+
+// visit counts a node visited by a query.
+func (m *Metrics) visit() {
+	if m != nil {
+		atomic.AddUint64(&m.nodesVisited, 1)
+	}
+}
+
+// split counts a node split.
+func (m *Metrics) split() {
+	if m != nil {
+		atomic.AddUint64(&m.splits, 1)
+	}
+}
+
+// collapse counts a node collapse.
+func (m *Metrics) collapse() {
+	if m != nil {
+		atomic.AddUint64(&m.collapses, 1)
+	}
+}
+
+// undo takes the given operation out of the entity count after it has been rolled back.
+//
+// The operation itself is still counted as it was run.
+func (m *Metrics) undo(op Op) {
+	if m == nil {
+		return
+	}
+
+	switch op {
+	case OpInsert:
+		atomic.AddInt64(&m.entities, -1)
+	case OpRemove:
+		atomic.AddInt64(&m.entities, 1)
+	}
+}
+
+// reset sets the entity count after the tree has been replaced.
+func (m *Metrics) reset(entities int) {
+	if m != nil {
+		atomic.StoreInt64(&m.entities, int64(entities))
+	}
+}
@@ -0,0 +1,147 @@
+package quadpix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestQuadpix_Metrics(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+	if tree.Metrics() != nil {
+		t.Fatalf("Quadpix.Metrics() = %v, want nil before EnableMetrics", tree.Metrics())
+	}
+
+	tree.Insert(pixel.R(10, 10, 50, 50))
+	m := tree.EnableMetrics()
+	if tree.EnableMetrics() != m {
+		t.Errorf("Quadpix.EnableMetrics() returned a new Metrics when already enabled")
+	}
+
+	a := &Entity{ID: 1, Rect: pixel.R(500, 400, 550, 450)}
+	b := &Entity{ID: 2, Rect: pixel.R(350, 250, 450, 350)}
+	tree.InsertEntities(a, b)
+	tree.Update(a, pixel.R(600, 400, 650, 450))
+	<-tree.Retrieve(pixel.R(0, 0, 100, 100))
+	<-tree.Intersects(pixel.R(0, 0, 100, 100))
+	<-tree.Intersect(pixel.R(0, 0, 100, 100))
+	<-tree.IsEntity(b)
+	tree.Remove(a)
+	if err := tree.Remove(a); err == nil {
+		t.Fatal("Quadpix.Remove() of a removed entity returned no error")
+	}
+
+	got := m.Snapshot()
+	want := MetricsSnapshot{
+		Inserts:          2,
+		Removes:          1,
+		Updates:          1,
+		Retrieves:        1,
+		Intersects:       1,
+		IntersectQueries: 1,
+		IsEntities:       1,
+		NodesVisited:     got.NodesVisited,
+		EntitiesFound:    3,
+		// the update collapses the root on remove and splits it again on insert
+		Splits:    2,
+		Collapses: 2,
+		Entities:  2,
+	}
+	if got != want {
+		t.Errorf("Metrics.Snapshot() = %+v, want %+v", got, want)
+	}
+	// each query visits the root and at least one leaf
+	if got.NodesVisited < 8 {
+		t.Errorf("Metrics.Snapshot().NodesVisited = %v, want at least %v", got.NodesVisited, 8)
+	}
+
+	tree.DisableMetrics()
+	tree.Insert(pixel.R(10, 10, 50, 50))
+	if m.Snapshot().Inserts != 2 || tree.Metrics() != nil {
+		t.Errorf("Quadpix.DisableMetrics() did not stop counting")
+	}
+}
+
+func TestQuadpix_MetricsInternal(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+	tree.Insert(pixel.R(10, 10, 50, 50))
+	tree.Insert(pixel.R(500, 400, 550, 450))
+	m := tree.EnableMetrics()
+
+	// work done for tooling is not counted as queries
+	<-tree.Explain(pixel.R(0, 0, 100, 100))
+	tree.shapes(DrawOptions{Highlights: []pixel.Rect{pixel.R(0, 0, 100, 100)}})
+
+	if got := m.Snapshot(); got != (MetricsSnapshot{Entities: 2}) {
+		t.Errorf("Metrics.Snapshot() after internal queries = %+v, want only %v entities", got, 2)
+	}
+}
+
+func TestQuadpix_MetricsRollback(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+	m := tree.EnableMetrics()
+
+	tx := tree.Begin()
+	tx.Insert(pixel.R(10, 10, 50, 50))
+	tx.Remove(&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)})
+	if err := tx.Commit(); err == nil {
+		t.Fatal("Tx.Commit() returned no error")
+	}
+
+	if got := m.Snapshot().Entities; got != 0 {
+		t.Errorf("Metrics.Snapshot().Entities after rollback = %v, want %v", got, 0)
+	}
+}
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	tests := []struct {
+		name string
+		tree string
+		want []string
+	}{
+		{
+			name: "no tree label",
+			tree: "",
+			want: []string{
+				"# TYPE quadpix_operations_total counter\n",
+				`quadpix_operations_total{op="insert"} 1` + "\n",
+				`quadpix_operations_total{op="retrieve"} 1` + "\n",
+				`quadpix_operations_total{op="intersects"} 0` + "\n",
+				"quadpix_query_entities_total 1\n",
+				"quadpix_splits_total 0\n",
+				"# TYPE quadpix_entities gauge\nquadpix_entities 1\n",
+			},
+		},
+		{
+			name: "tree label",
+			tree: "level",
+			want: []string{
+				`quadpix_operations_total{tree="level",op="insert"} 1` + "\n",
+				`quadpix_query_nodes_visited_total{tree="level"} 1` + "\n",
+				`quadpix_entities{tree="level"} 1` + "\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := New(800, 600, 2, 4)
+			m := tree.EnableMetrics()
+			tree.Insert(pixel.R(10, 10, 50, 50))
+			<-tree.Retrieve(pixel.R(0, 0, 100, 100))
+
+			var buf bytes.Buffer
+			if err := m.WritePrometheus(&buf, tt.tree); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Metrics.WritePrometheus() = %v, want it to contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
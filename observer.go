@@ -0,0 +1,161 @@
+package quadpix
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+)
+
+// EventType is the kind of change an Event describes.
+type EventType uint8
+
+const (
+	// EventInsert is sent after an entity is inserted in to the tree.
+	EventInsert EventType = iota
+	// EventRemove is sent after an entity is removed from the tree.
+	EventRemove
+	// EventSplit is sent after a node is split in to four children.
+	EventSplit
+	// EventCollapse is sent after the children of a node are collapsed back in to it.
+	EventCollapse
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventInsert:
+		return "insert"
+	case EventRemove:
+		return "remove"
+	case EventSplit:
+		return "split"
+	case EventCollapse:
+		return "collapse"
+	default:
+		return fmt.Sprintf("EventType(%d)", uint8(t))
+	}
+}
+
+// Event describes a change to the tree.
+//
+// For EventInsert and EventRemove, Nodes holds the bounds of the leafs covering the entity after the change,
+// or in a loose tree the bounds of the one node the entity belongs in.
+// For EventSplit and EventCollapse, Nodes holds the bounds of the node that was split or collapsed and
+// Entities the entities that were moved between it and its children.
+type Event struct {
+	Type     EventType
+	Nodes    []pixel.Rect
+	Entities Entities
+}
+
+// Observer receives the Events of a tree it has been added to.
+//
+// Events are sent on the thread changing the tree while the change is being made, so an Observer
+// must not change the tree itself. Use a CommandBuffer to queue changes in response to an Event.
+type Observer interface {
+	Observe(e Event)
+}
+
+// ObserverFunc is an adapter to use an ordinary function as an Observer.
+type ObserverFunc func(e Event)
+
+// Observe calls f(e).
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// observer is a registered Observer.
+type observer struct {
+	Observer
+}
+
+// AddObserver adds an Observer to the tree which will receive every following Event.
+//
+// An Update is sent as an EventRemove followed by an EventInsert. The Events of a transaction are only
+// sent once it has been committed successfully.
+//
+// AddObserver returns a function which removes the Observer from the tree again.
+func (q *Quadpix) AddObserver(o Observer) (remove func()) {
+	entry := &observer{o}
+	q.observers = append(q.observers, entry)
+
+	return func() {
+		for i := range q.observers {
+			if q.observers[i] == entry {
+				q.observers = append(q.observers[:i:i], q.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify sends the given Event to every Observer, or holds it back while a transaction is running.
+func (q *Quadpix) notify(e Event) {
+	if len(q.observers) == 0 {
+		return
+	}
+
+	if q.holding {
+		q.held = append(q.held, e)
+		return
+	}
+
+	for _, o := range q.observers {
+		o.Observe(e)
+	}
+}
+
+// notifyEntity sends an insert or remove Event for the given entity and bounds.
+func (q *Quadpix) notifyEntity(t EventType, entity *Entity, rect pixel.Rect) {
+	if len(q.observers) == 0 {
+		return
+	}
+
+	q.notify(Event{
+		Type:     t,
+		Nodes:    q.leaves(rect),
+		Entities: Entities{entity},
+	})
+}
+
+// hold starts holding back Events and returns true, or returns false if Events are already held back.
+func (q *Quadpix) hold() bool {
+	if q.holding {
+		return false
+	}
+
+	q.holding = true
+	return true
+}
+
+// release stops holding back Events if started is true and sends the held Events if send is true.
+func (q *Quadpix) release(started, send bool) {
+	if !started {
+		return
+	}
+
+	events := q.held
+	q.holding, q.held = false, nil
+
+	if send {
+		for _, e := range events {
+			q.notify(e)
+		}
+	}
+}
+
+// leaves returns the bounds of every leaf the given pixel.Rect intersects,
+// or for a loose tree the bounds of the node it belongs in.
+func (n *node) leaves(rect pixel.Rect) (bounds []pixel.Rect) {
+	if n.tree.looseness > 0 {
+		return []pixel.Rect{n.home(rect).rect}
+	}
+
+	if len(n.children) > 0 {
+		for _, child := range n.getQuadrant(rect) {
+			bounds = append(bounds, child.leaves(rect)...)
+		}
+		return
+	}
+
+	return []pixel.Rect{n.rect}
+}
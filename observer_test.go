@@ -0,0 +1,91 @@
+package quadpix
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestQuadpix_AddObserver(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+
+	var events []Event
+	remove := tree.AddObserver(ObserverFunc(func(e Event) {
+		events = append(events, e)
+	}))
+
+	a := &Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)}
+	b := &Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)}
+	c := &Entity{ID: 3, Rect: pixel.R(600, 10, 650, 50)}
+	tree.InsertEntities(a, b, c)
+	tree.Update(c, pixel.R(700, 10, 750, 50))
+	tree.Remove(b)
+
+	want := []struct {
+		typ      EventType
+		nodes    []pixel.Rect
+		entities int
+	}{
+		{EventInsert, []pixel.Rect{tree.rect}, 1},
+		{EventInsert, []pixel.Rect{tree.rect}, 1},
+		{EventSplit, []pixel.Rect{tree.rect}, 3},
+		{EventInsert, []pixel.Rect{pixel.R(400, 0, 800, 300)}, 1},
+		// the update removes c which collapses the root before inserting it again
+		{EventCollapse, []pixel.Rect{tree.rect}, 2},
+		{EventRemove, []pixel.Rect{tree.rect}, 1},
+		{EventSplit, []pixel.Rect{tree.rect}, 3},
+		{EventInsert, []pixel.Rect{pixel.R(400, 0, 800, 300)}, 1},
+		{EventCollapse, []pixel.Rect{tree.rect}, 2},
+		{EventRemove, []pixel.Rect{tree.rect}, 1},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("Quadpix.AddObserver() got %v events %+v, want %v", len(events), events, len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != w.typ || len(e.Entities) != w.entities || len(e.Nodes) != len(w.nodes) || e.Nodes[0] != w.nodes[0] {
+			t.Errorf("event %v = %v %v %v, want %v %v %v entities", i, e.Type, e.Nodes, len(e.Entities), w.typ, w.nodes, w.entities)
+		}
+	}
+
+	remove()
+	tree.Insert(pixel.R(0, 0, 10, 10))
+	if len(events) != len(want) {
+		t.Errorf("removed observer received %v more events", len(events)-len(want))
+	}
+}
+
+func TestQuadpix_AddObserverTx(t *testing.T) {
+	tests := []struct {
+		name       string
+		fail       bool
+		wantEvents int
+	}{
+		{name: "committed", fail: false, wantEvents: 2},
+		{name: "rolled back", fail: true, wantEvents: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := New(800, 600, 4, 4)
+
+			var events []Event
+			tree.AddObserver(ObserverFunc(func(e Event) {
+				events = append(events, e)
+			}))
+
+			tx := tree.Begin()
+			tx.Insert(pixel.R(10, 10, 50, 50))
+			tx.Insert(pixel.R(100, 10, 150, 50))
+			if tt.fail {
+				tx.Remove(&Entity{ID: 1, Rect: pixel.R(1, 1, 2, 2)})
+			}
+			tx.Commit()
+
+			if len(events) != tt.wantEvents {
+				t.Errorf("Tx.Commit() sent %v events, want %v", len(events), tt.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package quadpix
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// Option changes how a tree created with New stores its entities.
+//
+// Backends other then Quadpix, such as a Grid, use the Edges and Epsilon Options and ignore the rest.
+type Option func(c *config)
+
+// config holds the settings of a tree.
+type config struct {
+	maxEntities uint64
+	maxDepth    uint16
+
+	// looseness is the factor the bounds of each node are grown by in loose mode.
+	// When 0 each entity is stored in every leaf it intersects.
+	looseness float64
+
+	// overlap is the rules queries use to decide if an entity intersects the query bounds.
+	overlap Overlap
+}
+
+// accepts checks if a tree with this config and the given root bounds can hold an entity with the given bounds.
+//
+// Loose trees keep entities that do not fit in any child in the root, so any valid bounds are accepted.
+// Other trees store each entity in the leafs it reaches, so the bounds must also reach the root.
+func (c config) accepts(root, rect pixel.Rect) bool {
+	return validRect(rect) && (c.looseness > 0 || c.overlap.placement().Intersects(root, rect))
+}
+
+// Loose makes the tree a loose quadtree with node bounds grown by the given factor.
+//
+// In a loose tree each entity is stored in exactly one node, the deepest node whose grown bounds fully
+// contain the entity. Entities that do not fit in to any child of a node stay in that node even when it
+// has children, so large or boundary crossing entities are never copied in to many leafs. Queries check
+// the entities of every node whose grown bounds they intersect.
+//
+// A factor of 2 is a common choice. Loose panics if the factor is less then 1 or not finite.
+func Loose(factor float64) Option {
+	if !validLooseness(factor) || factor == 0 {
+		panic(fmt.Sprintf("quadpix: invalid looseness %v, must be at least 1", factor))
+	}
+
+	return func(c *config) {
+		c.looseness = factor
+	}
+}
+
+// Enclosing makes the tree store each entity in the smallest node that fully contains it.
+//
+// Entities that cross the bounds of a node's children stay in that node rather then being copied in to every
+// leaf they intersect, so no entity is ever stored more then once. Queries collect the entities held by each
+// node on the way down to the leafs.
+//
+// Enclosing is the same as a loose tree with a looseness of 1.
+func Enclosing() Option {
+	return Loose(1)
+}
+
+// Edges sets whether entities that only share an edge with the bounds of a query intersect it.
+//
+// The default is EdgesInclusive. Edges applies to every query on the tree.
+func Edges(mode EdgeMode) Option {
+	if mode > EdgesExclusive {
+		panic(fmt.Sprintf("quadpix: invalid edge mode %v", mode))
+	}
+
+	return func(c *config) {
+		c.overlap.Edges = mode
+	}
+}
+
+// Epsilon sets the tolerance used when checking if an entity intersects the bounds of a query.
+//
+// With EdgesInclusive entities up to epsilon away from the query bounds intersect it, and with
+// EdgesExclusive entities must overlap the query bounds by more then epsilon. Epsilon panics if
+// epsilon is negative or not finite.
+func Epsilon(epsilon float64) Option {
+	if !(Overlap{Epsilon: epsilon}).valid() {
+		panic(fmt.Sprintf("quadpix: invalid epsilon %v", epsilon))
+	}
+
+	return func(c *config) {
+		c.overlap.Epsilon = epsilon
+	}
+}
+
+// validLooseness checks that the given looseness is 0 or a finite factor of at least 1.
+func validLooseness(factor float64) bool {
+	return factor == 0 || (factor >= 1 && !math.IsInf(factor, 0))
+}
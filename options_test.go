@@ -0,0 +1,60 @@
+package quadpix
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestEnclosing(t *testing.T) {
+	tree := New(800, 600, 2, 4, Enclosing())
+
+	center := &Entity{ID: 1, Rect: pixel.R(350, 250, 450, 350)}
+	left := &Entity{ID: 2, Rect: pixel.R(10, 10, 50, 50)}
+	right := &Entity{ID: 3, Rect: pixel.R(500, 400, 550, 450)}
+	// crosses the center of the bottom left quadrant
+	quadrant := &Entity{ID: 4, Rect: pixel.R(150, 100, 250, 200)}
+	tree.InsertEntities(center, left, right, quadrant)
+
+	tests := []struct {
+		name   string
+		entity *Entity
+		want   pixel.Rect
+	}{
+		{name: "crossing the root center", entity: center, want: pixel.R(0, 0, 800, 600)},
+		{name: "inside one leaf", entity: left, want: pixel.R(0, 0, 400, 300)},
+		{name: "inside another leaf", entity: right, want: pixel.R(400, 300, 800, 600)},
+		{name: "crossing a quadrant center", entity: quadrant, want: pixel.R(0, 0, 400, 300)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var holders []pixel.Rect
+			tree.walk(func(n *node) {
+				if n.entities.has(tt.entity) {
+					holders = append(holders, n.rect)
+				}
+			})
+
+			if len(holders) != 1 || holders[0] != tt.want {
+				t.Errorf("entity %v stored in %v, want only %v", tt.entity.ID, holders, tt.want)
+			}
+		})
+	}
+
+	// queries collect the entities held by branches on the way down
+	got := <-tree.Retrieve(pixel.R(10, 10, 20, 20))
+	if !got.has(center) || !got.has(left) || !got.has(quadrant) || got.has(right) {
+		t.Errorf("Quadpix.Retrieve() = %v, want entities 1, 2 and 4", got)
+	}
+
+	// removing entities collapses the branches holding entities back in to the root
+	tree.Remove(right)
+	tree.Remove(left)
+	if len(tree.children) != 0 || len(tree.entities) != 2 {
+		t.Errorf("Quadpix.Remove() did not collapse the tree, root has %v children and %v entities", len(tree.children), len(tree.entities))
+	}
+	if err := tree.validate(); err != nil {
+		t.Errorf("Quadpix.validate() got error %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package quadpix
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// EdgeMode decides whether pixel.Rects that only share an edge or a corner intersect.
+type EdgeMode uint8
+
+const (
+	// EdgesInclusive counts pixel.Rects that share an edge as intersecting, the same as pixel.Rect.Intersects.
+	EdgesInclusive EdgeMode = iota
+	// EdgesExclusive only counts pixel.Rects that overlap by more then an edge as intersecting,
+	// so walls on a tile map that are next to each other do not collide.
+	EdgesExclusive
+)
+
+func (m EdgeMode) String() string {
+	switch m {
+	case EdgesInclusive:
+		return "inclusive"
+	case EdgesExclusive:
+		return "exclusive"
+	default:
+		return fmt.Sprintf("EdgeMode(%d)", uint8(m))
+	}
+}
+
+// Overlap holds the rules used to decide whether two pixel.Rects intersect.
+//
+// With EdgesInclusive, pixel.Rects with a gap of up to Epsilon between them still intersect.
+// With EdgesExclusive, pixel.Rects must overlap by more then Epsilon to intersect.
+// The zero Overlap is the same as pixel.Rect.Intersects.
+type Overlap struct {
+	Edges   EdgeMode
+	Epsilon float64
+}
+
+// Intersects checks if the two given pixel.Rects intersect under the rules of the Overlap.
+func (o Overlap) Intersects(a, b pixel.Rect) bool {
+	switch {
+	case o.Edges == EdgesExclusive:
+		return a.Min.X < b.Max.X-o.Epsilon && b.Min.X < a.Max.X-o.Epsilon &&
+			a.Min.Y < b.Max.Y-o.Epsilon && b.Min.Y < a.Max.Y-o.Epsilon
+	case o.Epsilon != 0:
+		return a.Min.X <= b.Max.X+o.Epsilon && b.Min.X <= a.Max.X+o.Epsilon &&
+			a.Min.Y <= b.Max.Y+o.Epsilon && b.Min.Y <= a.Max.Y+o.Epsilon
+	default:
+		return a.Intersects(b)
+	}
+}
+
+// placement returns the rules used to place entities in nodes and find the nodes a query reaches.
+//
+// Nodes are always matched inclusively so an entity touching a node is stored in it and a query
+// touching a node reaches it. Any entity intersecting a query under the tree's rules is then found
+// in a node the query reaches, even for pixel.Rects with no area on a node's edge.
+func (o Overlap) placement() Overlap {
+	if o.Edges == EdgesExclusive {
+		return Overlap{}
+	}
+	return o
+}
+
+// valid checks that the Overlap can be used by a tree.
+func (o Overlap) valid() bool {
+	return o.Edges <= EdgesExclusive && o.Epsilon >= 0 && !math.IsInf(o.Epsilon, 0)
+}
+
+// IntersectWith checks if any entity within entities intersects with the given pixel.Rect under the given Overlap rules.
+func (e Entities) IntersectWith(rect pixel.Rect, o Overlap) bool {
+	for i := range e {
+		if o.Intersects(e[i].Rect, rect) {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectsWith returns a list of all entities that intersect the given pixel.Rect under the given Overlap rules.
+func (e Entities) IntersectsWith(rect pixel.Rect, o Overlap) (entities Entities) {
+	for i := range e {
+		if o.Intersects(e[i].Rect, rect) {
+			entities = append(entities, e[i])
+		}
+	}
+	return
+}
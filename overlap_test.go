@@ -0,0 +1,194 @@
+package quadpix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestOverlap_Intersects(t *testing.T) {
+	wall := pixel.R(0, 0, 10, 10)
+
+	tests := []struct {
+		name    string
+		overlap Overlap
+		rect    pixel.Rect
+		want    bool
+	}{
+		{"inclusive overlap", Overlap{}, pixel.R(5, 5, 15, 15), true},
+		{"inclusive shared edge", Overlap{}, pixel.R(10, 0, 20, 10), true},
+		{"inclusive shared corner", Overlap{}, pixel.R(10, 10, 20, 20), true},
+		{"inclusive gap", Overlap{}, pixel.R(10.05, 0, 20, 10), false},
+		{"inclusive gap within epsilon", Overlap{Epsilon: 0.1}, pixel.R(10.05, 0, 20, 10), true},
+		{"inclusive gap past epsilon", Overlap{Epsilon: 0.1}, pixel.R(10.2, 0, 20, 10), false},
+		{"exclusive overlap", Overlap{Edges: EdgesExclusive}, pixel.R(5, 5, 15, 15), true},
+		{"exclusive shared edge", Overlap{Edges: EdgesExclusive}, pixel.R(10, 0, 20, 10), false},
+		{"exclusive shared corner", Overlap{Edges: EdgesExclusive}, pixel.R(10, 10, 20, 20), false},
+		{"exclusive point inside", Overlap{Edges: EdgesExclusive}, pixel.R(5, 5, 5, 5), true},
+		{"exclusive point on edge", Overlap{Edges: EdgesExclusive}, pixel.R(10, 5, 10, 5), false},
+		{"exclusive overlap within epsilon", Overlap{Edges: EdgesExclusive, Epsilon: 0.1}, pixel.R(9.95, 0, 20, 10), false},
+		{"exclusive overlap past epsilon", Overlap{Edges: EdgesExclusive, Epsilon: 0.1}, pixel.R(9.8, 0, 20, 10), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.overlap.Intersects(wall, tt.rect); got != tt.want {
+				t.Errorf("Overlap.Intersects(%v, %v) = %v, want %v", wall, tt.rect, got, tt.want)
+			}
+			if got := tt.overlap.Intersects(tt.rect, wall); got != tt.want {
+				t.Errorf("Overlap.Intersects(%v, %v) = %v, want %v", tt.rect, wall, got, tt.want)
+			}
+			if got := (Entities{{Rect: wall}}).IntersectWith(tt.rect, tt.overlap); got != tt.want {
+				t.Errorf("Entities.IntersectWith() = %v, want %v", got, tt.want)
+			}
+
+			// Intersect and Intersects follow the default Overlap rules
+			if tt.overlap == (Overlap{}) {
+				entities := Entities{{Rect: wall}}
+				if got := entities.Intersect(tt.rect); got != tt.want {
+					t.Errorf("Entities.Intersect() = %v, want %v", got, tt.want)
+				}
+				if got := len(entities.Intersects(tt.rect)) == 1; got != tt.want {
+					t.Errorf("Entities.Intersects() found %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEdges_tileMap(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{name: "inclusive", opts: nil, want: 2},
+		{name: "exclusive", opts: []Option{Edges(EdgesExclusive)}, want: 1},
+		{name: "exclusive loose", opts: []Option{Edges(EdgesExclusive), Loose(2)}, want: 1},
+		{name: "inclusive epsilon", opts: []Option{Epsilon(0.5)}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a row of touching 16 pixel wall tiles lined up with the node edges, with a gap before the last tile
+			tree := New(256, 256, 2, 4, tt.opts...)
+			for x := 0.0; x < 64; x += 16 {
+				tree.Insert(pixel.R(x, 0, x+16, 16))
+			}
+			tree.Insert(pixel.R(64.25, 0, 80, 16))
+
+			// a query covering the last touching tile exactly
+			got := <-tree.Intersects(pixel.R(48, 0, 64, 16))
+			if len(got) != tt.want {
+				t.Errorf("Quadpix.Intersects() = %v, want %v entities", got, tt.want)
+			}
+			if hit := <-tree.Intersect(pixel.R(64, 16, 64, 16)); hit == (tt.want == 1) {
+				t.Errorf("Quadpix.Intersect() of a tile corner = %v", hit)
+			}
+		})
+	}
+}
+
+func TestEdges_bruteForce(t *testing.T) {
+	overlaps := []Overlap{
+		{},
+		{Edges: EdgesExclusive},
+		{Epsilon: 2},
+		{Edges: EdgesExclusive, Epsilon: 2},
+	}
+	modes := [][]Option{nil, {Enclosing()}, {Loose(2)}}
+
+	for _, o := range overlaps {
+		for _, mode := range modes {
+			opts := append([]Option{Edges(o.Edges), Epsilon(o.Epsilon)}, mode...)
+			t.Run(fmt.Sprintf("%v %v %d", o.Edges, o.Epsilon, len(mode)), func(t *testing.T) {
+				r := rand.New(rand.NewSource(5))
+				tree := New(1024, 1024, 4, 6, opts...)
+
+				// rects snapped to a grid so many of them share edges with each other and with nodes
+				snapped := func(size float64) pixel.Rect {
+					x, y := math.Floor(r.Float64()*63)*16, math.Floor(r.Float64()*63)*16
+					w, h := math.Floor(r.Float64()*size)*16, math.Floor(r.Float64()*size)*16
+					return pixel.R(x, y, math.Min(x+w, 1024), math.Min(y+h, 1024))
+				}
+
+				entities := make(Entities, 300)
+				for i := range entities {
+					entities[i] = &Entity{ID: uint64(i + 1), Rect: snapped(4)}
+				}
+				tree.InsertEntities(entities...)
+
+				if err := tree.validate(); err != nil {
+					t.Fatalf("Quadpix.validate() got error %v", err)
+				}
+
+				for i := 0; i < 300; i++ {
+					query := snapped(8)
+
+					got := ids(<-tree.Intersects(query))
+					want := ids(entities.IntersectsWith(query, o))
+					if fmt.Sprint(got) != fmt.Sprint(want) {
+						t.Fatalf("Quadpix.Intersects(%v) = %v, want %v", query, got, want)
+					}
+					if hit := <-tree.Intersect(query); hit != (len(want) > 0) {
+						t.Fatalf("Quadpix.Intersect(%v) = %v, want %v", query, hit, len(want) > 0)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEdges_roundTrip(t *testing.T) {
+	tree := New(100, 100, 2, 4, Edges(EdgesExclusive), Epsilon(0.25))
+	tree.Insert(pixel.R(0, 0, 10, 10))
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := new(Quadpix)
+	if err := json.Unmarshal(data, fromJSON); err != nil {
+		t.Fatalf("json.Unmarshal() got error %v", err)
+	}
+
+	data, err = tree.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBinary := new(Quadpix)
+	if err := fromBinary.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Quadpix.UnmarshalBinary() got error %v", err)
+	}
+
+	for _, got := range []*Quadpix{fromJSON, fromBinary} {
+		if got.overlap != tree.overlap {
+			t.Errorf("loaded overlap = %+v, want %+v", got.overlap, tree.overlap)
+		}
+	}
+
+	for _, bad := range []string{`"edges":"sometimes"`, `"epsilon":-1`} {
+		data := []byte(`{"bounds":{"min":{"x":0,"y":0},"max":{"x":10,"y":10}},"maxEntities":2,"maxDepth":2,` + bad + `}`)
+		if err := json.Unmarshal(data, new(Quadpix)); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("json.Unmarshal() with %v got error %v, want %v", bad, err, ErrInvalidOption)
+		}
+	}
+}
+
+func TestEpsilon_panics(t *testing.T) {
+	for _, epsilon := range []float64{-1, math.Inf(1), math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Epsilon(%v) did not panic", epsilon)
+				}
+			}()
+			Epsilon(epsilon)
+		}()
+	}
+}
@@ -0,0 +1,273 @@
+package quadpix
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/faiface/pixel"
+)
+
+// Quadkey names a cell of a tree by the quadrants taken from the root to reach it, one digit per level.
+//
+// Each digit is the index of a child quadrant, in the same order nodes are split in:
+// 0 is the bottom left, 1 the bottom right, 2 the top left and 3 the top right.
+// The empty Quadkey is the root and the key "0213" is a cell at depth 4.
+//
+// A Quadkey only depends on the root bounds of a tree, so the same key names the same region
+// whether or not the tree has split that deep.
+type Quadkey string
+
+// MaxQuadkeyDepth is the max depth of a Quadkey that can be stored as an integer with Uint64.
+const MaxQuadkeyDepth = 31
+
+// ParseQuadkey checks the given string is made of only the digits 0 to 3 and returns it as a Quadkey.
+//
+// ParseQuadkey returns an error wrapping ErrInvalidQuadkey for any other string.
+func ParseQuadkey(s string) (Quadkey, error) {
+	k := Quadkey(s)
+	if !k.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidQuadkey, s)
+	}
+	return k, nil
+}
+
+// QuadkeyFromUint64 returns the Quadkey stored in the given integer by Quadkey.Uint64.
+//
+// QuadkeyFromUint64 returns an error wrapping ErrInvalidQuadkey if the integer is not a valid code.
+func QuadkeyFromUint64(code uint64) (Quadkey, error) {
+	// the highest set bit marks the depth of the key and must be at an even position
+	n := bits.Len64(code) - 1
+	if n < 0 || n%2 != 0 {
+		return "", fmt.Errorf("%w: code %d", ErrInvalidQuadkey, code)
+	}
+
+	digits := make([]byte, n/2)
+	for i := range digits {
+		digits[i] = '0' + byte(code>>uint(n-2*(i+1))&3)
+	}
+	return Quadkey(digits), nil
+}
+
+// Valid checks if the Quadkey is made of only the digits 0 to 3.
+func (k Quadkey) Valid() bool {
+	for i := 0; i < len(k); i++ {
+		if k[i] < '0' || k[i] > '3' {
+			return false
+		}
+	}
+	return true
+}
+
+// Depth returns the depth of the cell the Quadkey names, 0 for the root.
+func (k Quadkey) Depth() int {
+	return len(k)
+}
+
+// Parent returns the Quadkey of the cell holding this one. The parent of the root is the root.
+func (k Quadkey) Parent() Quadkey {
+	if len(k) == 0 {
+		return k
+	}
+	return k[:len(k)-1]
+}
+
+// Child returns the Quadkey of the given quadrant, from 0 to 3, of this cell.
+//
+// Child panics if the quadrant is not from 0 to 3.
+func (k Quadkey) Child(quadrant int) Quadkey {
+	if quadrant < 0 || quadrant > 3 {
+		panic(fmt.Sprintf("quadpix: invalid quadrant %d", quadrant))
+	}
+	return k + Quadkey('0'+byte(quadrant))
+}
+
+// Contains checks if the given Quadkey names this cell or a cell with in it.
+func (k Quadkey) Contains(other Quadkey) bool {
+	return strings.HasPrefix(string(other), string(k))
+}
+
+// Uint64 returns the Quadkey as an integer, made of two bits per digit below a single set bit marking the depth.
+//
+// The root is 1 and every other key maps to a different integer, so codes can be used as map keys or sent over the network.
+// Uint64 returns 0 if the Quadkey is not valid or deeper then MaxQuadkeyDepth.
+func (k Quadkey) Uint64() uint64 {
+	if len(k) > MaxQuadkeyDepth || !k.Valid() {
+		return 0
+	}
+
+	code := uint64(1)
+	for i := 0; i < len(k); i++ {
+		code = code<<2 | uint64(k[i]-'0')
+	}
+	return code
+}
+
+// QuadkeyAt returns the Quadkey of the cell at the given depth holding the given point.
+//
+// Points on the edge between two cells belong to the right or top cell, the same cell an entity centered on
+// the point would be placed in. QuadkeyAt returns an error wrapping ErrInvalidBounds if the point is not with
+// in the root bounds of the tree.
+func (q *Quadpix) QuadkeyAt(point pixel.Vec, depth uint16) (Quadkey, error) {
+	if !q.rect.Contains(point) {
+		return "", fmt.Errorf("%w: point %v is not with in the tree bounds %v", ErrInvalidBounds, point, q.rect)
+	}
+
+	digits := make([]byte, depth)
+	rect := q.rect
+	for i := range digits {
+		c := rect.Center()
+
+		quadrant := 0
+		if point.X >= c.X {
+			quadrant |= 1
+		}
+		if point.Y >= c.Y {
+			quadrant |= 2
+		}
+
+		digits[i] = '0' + byte(quadrant)
+		rect = quadrantRect(rect, quadrant)
+	}
+
+	return Quadkey(digits), nil
+}
+
+// QuadkeyOf returns the Quadkey of the smallest cell, no deeper then the given depth, fully containing the given pixel.Rect.
+//
+// A pixel.Rect on the edge between two cells belongs to the cell holding its center, so a point gets the same
+// Quadkey as from QuadkeyAt.
+//
+// QuadkeyOf returns an error wrapping ErrInvalidBounds if the pixel.Rect is not valid or not fully with in the root
+// bounds of the tree.
+func (q *Quadpix) QuadkeyOf(rect pixel.Rect, depth uint16) (Quadkey, error) {
+	if !validRect(rect) || !encloses(q.rect, rect) {
+		return "", fmt.Errorf("%w: %v is not with in the tree bounds %v", ErrInvalidBounds, rect, q.rect)
+	}
+
+	var digits []byte
+	cell := q.rect
+	for len(digits) < int(depth) {
+		// only the cell holding the center of the pixel.Rect can contain it, with edges going to the
+		// right or top cell the same as QuadkeyAt.
+		c, center := cell.Center(), rect.Center()
+
+		quadrant := 0
+		if center.X >= c.X {
+			quadrant |= 1
+		}
+		if center.Y >= c.Y {
+			quadrant |= 2
+		}
+		if !encloses(quadrantRect(cell, quadrant), rect) {
+			break
+		}
+
+		digits = append(digits, '0'+byte(quadrant))
+		cell = quadrantRect(cell, quadrant)
+	}
+
+	return Quadkey(digits), nil
+}
+
+// QuadkeyRect returns the bounds of the cell the given Quadkey names.
+//
+// QuadkeyRect returns an error wrapping ErrInvalidQuadkey if the Quadkey is not valid.
+func (q *Quadpix) QuadkeyRect(key Quadkey) (pixel.Rect, error) {
+	if !key.Valid() {
+		return pixel.Rect{}, fmt.Errorf("%w: %q", ErrInvalidQuadkey, string(key))
+	}
+
+	rect := q.rect
+	for i := 0; i < len(key); i++ {
+		rect = quadrantRect(rect, int(key[i]-'0'))
+	}
+	return rect, nil
+}
+
+// Node returns the NodeInfo of the node the given Quadkey names.
+//
+// The channel holds nil if the Quadkey is not valid or the tree has not split deep enough to have the node.
+//
+// Node returns a channel of a NodeInfo. This is due to the fact that all Read-Only operations in Quadpix are run on there own thread.
+func (q *Quadpix) Node(key Quadkey) <-chan *NodeInfo {
+	out := make(chan *NodeInfo)
+
+	go func() {
+		var info *NodeInfo
+		if n, depth := q.lookup(key); depth == len(key) && key.Valid() {
+			i := n.info()
+			info = &i
+		}
+
+		out <- info
+		close(out)
+	}()
+
+	return out
+}
+
+// EntitiesIn returns every entity stored under the cell the given Quadkey names, without duplicates.
+//
+// If the tree has a node for the Quadkey, the entities stored in that node and all its children are returned.
+// If the tree has not split that deep, the entities of the leaf holding the cell that reach the cell are returned.
+// Entities stored in nodes above the cell, like large entities in a loose tree, are not under it and not returned.
+//
+// The channel holds nil if the Quadkey is not valid.
+//
+// EntitiesIn returns a channel of Entities. This is due to the fact that all Read-Only operations in Quadpix are run on there own thread.
+func (q *Quadpix) EntitiesIn(key Quadkey) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		var entities Entities
+		if key.Valid() {
+			n, depth := q.lookup(key)
+			if depth == len(key) {
+				entities = n.all()
+			} else {
+				cell, _ := q.QuadkeyRect(key)
+				for _, e := range n.entities {
+					if q.overlap.placement().Intersects(cell, e.Rect) {
+						entities = append(entities, e)
+					}
+				}
+			}
+		}
+
+		out <- entities
+		close(out)
+	}()
+
+	return out
+}
+
+// lookup follows the given Quadkey down the tree and returns the deepest node reached and the number of digits followed.
+//
+// The Quadkey must be checked with Valid by the caller before the result is used.
+func (q *Quadpix) lookup(key Quadkey) (*node, int) {
+	n := q.node
+	for i := 0; i < len(key); i++ {
+		quadrant := int(key[i] - '0')
+		if len(n.children) == 0 || quadrant < 0 || quadrant > 3 {
+			return n, i
+		}
+		n = n.children[quadrant]
+	}
+	return n, len(key)
+}
+
+// quadrantRect returns the bounds of the given quadrant of the given pixel.Rect, matching the bounds given to children by split.
+func quadrantRect(rect pixel.Rect, quadrant int) pixel.Rect {
+	c := rect.Center()
+	switch quadrant {
+	case 0:
+		return pixel.R(rect.Min.X, rect.Min.Y, c.X, c.Y)
+	case 1:
+		return pixel.R(c.X, rect.Min.Y, rect.Max.X, c.Y)
+	case 2:
+		return pixel.R(rect.Min.X, c.Y, c.X, rect.Max.Y)
+	default:
+		return pixel.R(c.X, c.Y, rect.Max.X, rect.Max.Y)
+	}
+}
@@ -0,0 +1,263 @@
+package quadpix
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestQuadkey_Uint64(t *testing.T) {
+	tests := []struct {
+		key  Quadkey
+		want uint64
+	}{
+		{key: "", want: 1},
+		{key: "0", want: 4},
+		{key: "3", want: 7},
+		{key: "0213", want: 1<<8 | 0<<6 | 2<<4 | 1<<2 | 3},
+		{key: "0214", want: 0},
+		{key: "00000000000000000000000000000000", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			got := tt.key.Uint64()
+			if got != tt.want {
+				t.Fatalf("Uint64() = %d, want %d", got, tt.want)
+			}
+			if got == 0 {
+				return
+			}
+
+			back, err := QuadkeyFromUint64(got)
+			if err != nil || back != tt.key {
+				t.Errorf("QuadkeyFromUint64(%d) = %q, %v, want %q", got, back, err, tt.key)
+			}
+		})
+	}
+
+	for _, code := range []uint64{0, 2, 3, 8} {
+		if _, err := QuadkeyFromUint64(code); !errors.Is(err, ErrInvalidQuadkey) {
+			t.Errorf("QuadkeyFromUint64(%d) error = %v, want %v", code, err, ErrInvalidQuadkey)
+		}
+	}
+}
+
+func TestQuadkey_Family(t *testing.T) {
+	k, err := ParseQuadkey("021")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseQuadkey("02a"); !errors.Is(err, ErrInvalidQuadkey) {
+		t.Errorf("ParseQuadkey() error = %v, want %v", err, ErrInvalidQuadkey)
+	}
+
+	if k.Depth() != 3 || k.Parent() != "02" || k.Child(3) != "0213" || Quadkey("").Parent() != "" {
+		t.Errorf("Depth() = %d, Parent() = %q, Child(3) = %q", k.Depth(), k.Parent(), k.Child(3))
+	}
+	if !k.Contains("0213") || !k.Contains(k) || k.Contains("02") || !Quadkey("").Contains(k) {
+		t.Error("Contains() does not match key prefixes")
+	}
+}
+
+func TestQuadpix_QuadkeyAt(t *testing.T) {
+	q := New(800, 600, 1, 8)
+
+	tests := []struct {
+		name  string
+		point pixel.Vec
+		depth uint16
+		want  Quadkey
+	}{
+		{name: "root", point: pixel.V(10, 10), depth: 0, want: ""},
+		{name: "bottom left", point: pixel.V(10, 10), depth: 2, want: "00"},
+		{name: "top right", point: pixel.V(790, 590), depth: 2, want: "33"},
+		{name: "mixed", point: pixel.V(450, 100), depth: 2, want: "10"},
+		{name: "center goes top right", point: pixel.V(400, 300), depth: 1, want: "3"},
+		{name: "max corner", point: pixel.V(800, 600), depth: 1, want: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := q.QuadkeyAt(tt.point, tt.depth)
+			if err != nil || got != tt.want {
+				t.Fatalf("QuadkeyAt() = %q, %v, want %q", got, err, tt.want)
+			}
+
+			cell, _ := q.QuadkeyRect(got)
+			if !cell.Contains(tt.point) {
+				t.Errorf("QuadkeyRect(%q) = %v does not contain %v", got, cell, tt.point)
+			}
+		})
+	}
+
+	if _, err := q.QuadkeyAt(pixel.V(-1, 10), 2); !errors.Is(err, ErrInvalidBounds) {
+		t.Errorf("QuadkeyAt() error = %v, want %v", err, ErrInvalidBounds)
+	}
+}
+
+func TestQuadpix_QuadkeyOf(t *testing.T) {
+	q := New(800, 600, 1, 8)
+
+	tests := []struct {
+		name  string
+		rect  pixel.Rect
+		depth uint16
+		want  Quadkey
+	}{
+		{name: "small", rect: pixel.R(10, 10, 20, 20), depth: 3, want: "000"},
+		{name: "depth limit", rect: pixel.R(10, 10, 20, 20), depth: 1, want: "0"},
+		{name: "straddles center", rect: pixel.R(390, 290, 410, 310), depth: 5, want: ""},
+		{name: "straddles child", rect: pixel.R(190, 10, 210, 20), depth: 5, want: "0"},
+		{name: "whole root", rect: pixel.R(0, 0, 800, 600), depth: 5, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := q.QuadkeyOf(tt.rect, tt.depth)
+			if err != nil || got != tt.want {
+				t.Fatalf("QuadkeyOf() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := q.QuadkeyOf(pixel.R(790, 10, 810, 20), 2); !errors.Is(err, ErrInvalidBounds) {
+		t.Errorf("QuadkeyOf() error = %v, want %v", err, ErrInvalidBounds)
+	}
+}
+
+func TestQuadpix_QuadkeyRect(t *testing.T) {
+	q := New(800, 600, 1, 8)
+
+	tests := []struct {
+		key  Quadkey
+		want pixel.Rect
+	}{
+		{key: "", want: pixel.R(0, 0, 800, 600)},
+		{key: "1", want: pixel.R(400, 0, 800, 300)},
+		{key: "2", want: pixel.R(0, 300, 400, 600)},
+		{key: "30", want: pixel.R(400, 300, 600, 450)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			got, err := q.QuadkeyRect(tt.key)
+			if err != nil || got != tt.want {
+				t.Errorf("QuadkeyRect() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := q.QuadkeyRect("4"); !errors.Is(err, ErrInvalidQuadkey) {
+		t.Errorf("QuadkeyRect() error = %v, want %v", err, ErrInvalidQuadkey)
+	}
+}
+
+func TestQuadpix_Node(t *testing.T) {
+	q := New(800, 600, 1, 8)
+	q.Insert(pixel.R(10, 10, 20, 20))
+	q.Insert(pixel.R(500, 400, 510, 410))
+
+	// every node of the tree can be found by its key
+	for _, info := range <-q.Nodes() {
+		key, err := q.QuadkeyOf(info.Bounds, uint16(info.Depth))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := <-q.Node(key)
+		if got == nil || *got != info {
+			t.Errorf("Node(%q) = %v, want %v", key, got, info)
+		}
+	}
+
+	for _, key := range []Quadkey{"00", "x"} {
+		if got := <-q.Node(key); got != nil {
+			t.Errorf("Node(%q) = %v, want nil", key, got)
+		}
+	}
+}
+
+func TestQuadpix_EntitiesIn(t *testing.T) {
+	q := New(800, 600, 1, 8)
+	a := E(pixel.R(10, 10, 20, 20))
+	b := E(pixel.R(500, 400, 510, 410))
+	c := E(pixel.R(350, 250, 450, 350))
+	if err := q.InsertEntities(a, b, c); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  Quadkey
+		want Entities
+	}{
+		{key: "", want: Entities{a, b, c}},
+		{key: "0", want: Entities{a, c}},
+		{key: "3", want: Entities{b, c}},
+		// deeper then the tree has split
+		{key: "000", want: Entities{a}},
+		{key: "033", want: Entities{c}},
+		{key: "011", want: nil},
+		{key: "9", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			got := <-q.EntitiesIn(tt.key)
+			if len(got) != len(tt.want) {
+				t.Fatalf("EntitiesIn() = %v, want %v", got, tt.want)
+			}
+			for _, e := range tt.want {
+				if !got.Contains(e) {
+					t.Errorf("EntitiesIn() = %v, missing %v", got, e)
+				}
+			}
+		})
+	}
+}
+
+func TestQuadpix_QuadkeyOfEdges(t *testing.T) {
+	modes := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default"},
+		{name: "enclosing", opts: []Option{Enclosing()}},
+		{name: "loose", opts: []Option{Loose(2)}},
+	}
+	// points and rects on the edges of cells at several depths
+	edges := []pixel.Rect{
+		pixel.R(50, 25, 50, 25),
+		pixel.R(50, 50, 50, 50),
+		pixel.R(25, 75, 25, 75),
+		pixel.R(45, 25, 50, 30),
+		pixel.R(50, 10, 55, 12.5),
+		pixel.R(62.5, 62.5, 75, 75),
+	}
+
+	for _, mode := range modes {
+		t.Run(mode.name, func(t *testing.T) {
+			q := New(100, 100, 1, 4, mode.opts...)
+			for _, rect := range edges {
+				if err := q.InsertEntities(E(rect)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, e := range q.all() {
+				if e.Rect.W() == 0 && e.Rect.H() == 0 {
+					at, _ := q.QuadkeyAt(e.Rect.Min, 3)
+					if of, _ := q.QuadkeyOf(e.Rect, 3); of != at {
+						t.Errorf("QuadkeyOf(%v) = %q, QuadkeyAt() = %q", e.Rect, of, at)
+					}
+				}
+
+				for depth := uint16(0); depth <= 4; depth++ {
+					key, err := q.QuadkeyOf(e.Rect, depth)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !(<-q.EntitiesIn(key)).Contains(e) {
+						t.Errorf("EntitiesIn(QuadkeyOf(%v, %d) = %q) is missing the entity", e.Rect, depth, key)
+					}
+				}
+			}
+		})
+	}
+}
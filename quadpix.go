@@ -1,14 +1,31 @@
 package quadpix
 
 import (
+	"fmt"
+
 	"github.com/faiface/pixel"
 )
 
 // Quadpix is the core structure holding the quadtree data for quadpix.
 type Quadpix struct {
 	*node
+	config
+
+	// splitLimit is the max number of splits allowed when not 0.
+	// It is used to bound the work of loading untrusted data.
+	splitLimit int
+	splits     int
+
+	history  *History
+	metrics  *Metrics
+	recorder *Recorder
+
+	observers []*observer
+	holding   bool
+	held      []Event
 
-	maxDepth uint16
+	trackDirty bool
+	dirty      []pixel.Rect
 }
 
 // New creates a new instance of Quadpix with the given arguments.
@@ -17,19 +34,30 @@ type Quadpix struct {
 //		- Width, height float64: the width and height of the root of the tree.
 //		- maxEntities uint64: the max number of entities per node for the tree before the node splits.
 //		- maxDepth uin16: the max depth of the tree.
+//		- opts ...Option: any number of Options changing how the tree stores its entities.
 //
 // Returns:
 //		-Pointer to the newly created Quadpix instance.
-func New(width, height float64, maxEntities uint64, maxDepth uint16) *Quadpix {
-	return &Quadpix{
-		node: &node{
-			rect:     pixel.R(0, 0, width, height),
-			entities: make(Entities, 0, maxEntities),
-			children: make([]*node, 0, 4),
-			depth:    0,
-		},
-		maxDepth: maxDepth,
+func New(width, height float64, maxEntities uint64, maxDepth uint16, opts ...Option) *Quadpix {
+	cfg := config{
+		maxEntities: maxEntities,
+		maxDepth:    maxDepth,
 	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return newQuadpix(pixel.R(0, 0, width, height), cfg)
+}
+
+// newQuadpix creates a new instance of Quadpix with the given root bounds and config.
+func newQuadpix(rect pixel.Rect, cfg config) *Quadpix {
+	q := &Quadpix{
+		config: cfg,
+	}
+	q.node = q.newRoot(rect, cfg.maxEntities)
+
+	return q
 }
 
 // Insert adds the given pixel.Rect to the tree as an entity bound.
@@ -39,21 +67,34 @@ func New(width, height float64, maxEntities uint64, maxDepth uint16) *Quadpix {
 //
 // If no Actions are given it will set set to nil.
 func (q *Quadpix) Insert(rect pixel.Rect, action ...Action) {
-	q.insert(E(rect, action...), q.maxDepth)
+	entity := E(rect, action...)
+	q.insert(entity)
+
+	q.record(record{op: OpInsert, entity: entity, to: rect})
 }
 
 // InsertEntities inserts any number of Entity's to the tree.
 //
-// This function will return an error if no entities are given to InsertEntities.
+// Entities that have Behaviours but no Actions get their Actions bound from their Behaviours first.
+//
+// This function will return an error if no entities are given to InsertEntities or if a Behaviour
+// can not be bound. On error no entities are inserted.
 func (q *Quadpix) InsertEntities(entities ...*Entity) error {
 	// Check for no entities given.
 	if len(entities) == 0 {
 		return ErrNoEntitiesGiven
 	}
 
+	// Bind behaviours before changing the tree.
+	if err := bindEntities(entities); err != nil {
+		return err
+	}
+
 	// Add entities to tree.
 	for _, e := range entities {
-		q.insert(e, q.maxDepth)
+		q.insert(e)
+
+		q.record(record{op: OpInsert, entity: e, to: e.Rect})
 	}
 
 	return nil
@@ -67,7 +108,40 @@ func (q *Quadpix) InsertEntities(entities ...*Entity) error {
 // If you whish to remove a given entity from the tree you must make sure you have at least the same ID and pixel.Rect
 // as the entity you are trying to remove.
 func (q *Quadpix) Remove(entity *Entity) error {
-	return q.remove(entity)
+	if err := q.remove(entity); err != nil {
+		return err
+	}
+
+	q.record(record{op: OpRemove, entity: entity, from: entity.Rect})
+
+	return nil
+}
+
+// Update moves the given entity to the new pixel.Rect bounds within the tree.
+//
+// Update will return an error if the given entity can not be found in the tree, or an error wrapping
+// ErrInvalidBounds if the tree can not hold the new bounds. Like Remove the given entity must have the
+// same ID and pixel.Rect as the entity stored in the tree. On success the given entity's Rect is set to the new bounds.
+func (q *Quadpix) Update(entity *Entity, rect pixel.Rect) error {
+	// check the new bounds before the entity is taken out of the tree
+	if !q.accepts(rect) {
+		return fmt.Errorf("%w: entity %v can not be moved to %v", ErrInvalidBounds, entity.ID, rect)
+	}
+
+	// remove the entity from its old position
+	if err := q.remove(entity); err != nil {
+		return err
+	}
+	q.notifyEntity(EventRemove, entity, entity.Rect)
+
+	// re-insert the entity with its new bounds
+	old := entity.Rect
+	entity.Rect = rect
+	q.insert(entity)
+
+	q.record(record{op: OpUpdate, entity: entity, from: old, to: rect})
+
+	return nil
 }
 
 // Retrieve gets all entities from all leafs the given rect intersects with within the tree.
@@ -78,7 +152,11 @@ func (q *Quadpix) Retrieve(rect pixel.Rect) <-chan Entities {
 	out := make(chan Entities)
 
 	go func() {
-		out <- q.retrieve(rect)
+		entities := q.retrieve(rect, q.metrics)
+		q.metrics.retrieve(len(entities))
+		q.recorder.query(eventRetrieve, rect, entities)
+
+		out <- entities
 		close(out)
 	}()
 
@@ -92,7 +170,12 @@ func (q *Quadpix) Intersect(rect pixel.Rect) <-chan bool {
 	out := make(chan bool)
 
 	go func() {
-		out <- q.intersect(rect)
+		q.metrics.intersect()
+
+		hit := q.intersect(rect, q.metrics)
+		q.recorder.hit(eventIntersect, rect, 0, hit)
+
+		out <- hit
 		close(out)
 	}()
 
@@ -106,7 +189,11 @@ func (q *Quadpix) Intersects(rect pixel.Rect) <-chan Entities {
 	out := make(chan Entities)
 
 	go func() {
-		out <- q.retrieve(rect).Intersects(rect)
+		entities := q.retrieve(rect, q.metrics).IntersectsWith(rect, q.overlap)
+		q.metrics.intersectQuery(len(entities))
+		q.recorder.query(eventIntersects, rect, entities)
+
+		out <- entities
 		close(out)
 	}()
 
@@ -124,7 +211,12 @@ func (q *Quadpix) IsEntity(entity *Entity) <-chan bool {
 	out := make(chan bool)
 
 	go func() {
-		out <- q.isEntity(entity)
+		q.metrics.isEntity()
+
+		found := q.isEntity(entity, q.metrics)
+		q.recorder.hit(eventIsEntity, entity.Rect, entity.ID, found)
+
+		out <- found
 		close(out)
 	}()
 
@@ -133,15 +225,31 @@ func (q *Quadpix) IsEntity(entity *Entity) <-chan bool {
 
 // tree node
 type node struct {
+	tree *Quadpix
+
 	rect     pixel.Rect
 	entities Entities
 	children []*node
 	depth    uint16
 }
 
+// create a new empty root node for the tree with the given pixel.Rect bounds.
+//
+// size is the starting capacity of the nodes list of entities.
+func (q *Quadpix) newRoot(rect pixel.Rect, size uint64) *node {
+	return &node{
+		tree:     q,
+		rect:     rect,
+		entities: make(Entities, 0, size),
+		children: make([]*node, 0, 4),
+		depth:    0,
+	}
+}
+
 // create new node from given pixel.Rect bounds and prior nodes data.
 func (n *node) new(rect pixel.Rect) *node {
 	return &node{
+		tree:     n.tree,
 		rect:     rect,
 		entities: make(Entities, 0, cap(n.entities)),
 		children: make([]*node, 0, 4),
@@ -149,8 +257,46 @@ func (n *node) new(rect pixel.Rect) *node {
 	}
 }
 
+// clone creates a deep copy of this node and all of its children for the given tree.
+//
+// If copies is nil the entities themselves are shared between the copy and this node. Otherwise each
+// entity is replaced by a copy, kept in copies so an entity stored in several nodes is copied once.
+func (n *node) clone(tree *Quadpix, copies map[*Entity]*Entity) *node {
+	c := &node{
+		tree:     tree,
+		rect:     n.rect,
+		entities: make(Entities, len(n.entities), cap(n.entities)),
+		children: make([]*node, 0, cap(n.children)),
+		depth:    n.depth,
+	}
+	copy(c.entities, n.entities)
+
+	if copies != nil {
+		for i, e := range c.entities {
+			cp, ok := copies[e]
+			if !ok {
+				cp = e.copy()
+				copies[e] = cp
+			}
+			c.entities[i] = cp
+		}
+	}
+
+	for i := range n.children {
+		c.children = append(c.children, n.children[i].clone(tree, copies))
+	}
+
+	return c
+}
+
 // recessive function for inserting entity's in to the tree.
-func (n *node) insert(entity *Entity, maxDepth uint16) {
+func (n *node) insert(entity *Entity) {
+	// loose trees store each entity in a single node
+	if n.tree.looseness > 0 {
+		n.insertLoose(entity)
+		return
+	}
+
 	// check for if you are at a leaf node.
 	if len(n.children) > 0 {
 		// find children the given entity's pixel.Rect intersects.
@@ -164,18 +310,31 @@ func (n *node) insert(entity *Entity, maxDepth uint16) {
 
 		// recursive call to insert for each child node found.
 		for i := range nodes {
-			nodes[i].insert(entity, maxDepth)
+			nodes[i].insert(entity)
 		}
 		return
 	}
 
 	// check for a needed split
-	if len(n.entities)+1 > cap(n.entities) && n.depth < maxDepth {
+	if uint64(len(n.entities)+1) > n.tree.maxEntities && n.depth < n.tree.maxDepth && n.canSplit(entity) {
 		// split node in to its children
 		n.split()
 
 		// move this nodes entities to the new children nodes.
-		n.moveEntities(append(n.entities, entity))
+		moved := append(n.entities, entity)
+		if n.tree.splitLimit == 0 {
+			n.moveEntities(moved)
+			n.tree.notify(Event{Type: EventSplit, Nodes: []pixel.Rect{n.rect}, Entities: moved})
+			return
+		}
+
+		// while the split limit is set the entities are inserted in to the children, so a child given
+		// more then maxEntities entities splits as well if that separates them.
+		n.tree.notify(Event{Type: EventSplit, Nodes: []pixel.Rect{n.rect}, Entities: moved})
+		for _, e := range moved {
+			n.insert(e)
+		}
+		n.entities = n.entities[:0]
 		return
 	}
 
@@ -185,12 +344,72 @@ func (n *node) insert(entity *Entity, maxDepth uint16) {
 
 // split this nodes children in to there corresponding child quadrant nodes.
 func (n *node) split() {
-	n.children = append(n.children,
-		n.new(pixel.R(n.rect.Min.X, n.rect.Min.Y, n.rect.Center().X, n.rect.Center().Y)),
-		n.new(pixel.R(n.rect.Center().X, n.rect.Min.Y, n.rect.Max.X, n.rect.Center().Y)),
-		n.new(pixel.R(n.rect.Min.X, n.rect.Center().Y, n.rect.Center().X, n.rect.Max.Y)),
-		n.new(pixel.R(n.rect.Center().X, n.rect.Center().Y, n.rect.Max.X, n.rect.Max.Y)),
-	)
+	n.tree.splits++
+	n.tree.metrics.split()
+
+	for _, rect := range n.quadrants() {
+		n.children = append(n.children, n.new(rect))
+	}
+}
+
+// quadrants returns the bounds of the four children this node has once split.
+func (n *node) quadrants() [4]pixel.Rect {
+	return [4]pixel.Rect{
+		pixel.R(n.rect.Min.X, n.rect.Min.Y, n.rect.Center().X, n.rect.Center().Y),
+		pixel.R(n.rect.Center().X, n.rect.Min.Y, n.rect.Max.X, n.rect.Center().Y),
+		pixel.R(n.rect.Min.X, n.rect.Center().Y, n.rect.Center().X, n.rect.Max.Y),
+		pixel.R(n.rect.Center().X, n.rect.Center().Y, n.rect.Max.X, n.rect.Max.Y),
+	}
+}
+
+// canSplit checks if the tree is still allowed to split nodes.
+func (q *Quadpix) canSplit() bool {
+	return q.splitLimit == 0 || q.splits < q.splitLimit
+}
+
+// canSplit checks if this leaf is allowed to split to make room for the given entity.
+//
+// While the split limit is set a leaf is also only split when that separates its entities.
+func (n *node) canSplit(entity *Entity) bool {
+	if n.tree.splitLimit == 0 {
+		return true
+	}
+
+	return n.tree.canSplit() && n.separates(entity)
+}
+
+// separates checks if splitting this leaf would store the given entity in other quadrants then its entities.
+//
+// A split that stores every entity in the same quadrants does not separate them and only copies them
+// in to the children, as happens with duplicate entities or entities larger then the leaf.
+func (n *node) separates(entity *Entity) bool {
+	quadrants := n.quadrantMask(entity.Rect)
+
+	// a leaf holding more then maxEntities entities has not been split because of this check,
+	// so all of its entities are stored in the same quadrants. Entities are not moved in to
+	// such a leaf by a split, as splits insert them while the split limit is set.
+	entities := n.entities
+	if uint64(len(entities)) > n.tree.maxEntities {
+		entities = entities[:1]
+	}
+
+	for _, e := range entities {
+		if n.quadrantMask(e.Rect) != quadrants {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quadrantMask returns a bit for each quadrant of this node the given pixel.Rect is stored in once the node splits.
+func (n *node) quadrantMask(rect pixel.Rect) (quadrants uint8) {
+	for i, quadrant := range n.quadrants() {
+		if n.tree.overlap.placement().Intersects(quadrant, rect) {
+			quadrants |= 1 << uint(i)
+		}
+	}
+	return
 }
 
 // move given entities to this nodes children.
@@ -219,6 +438,10 @@ func (n *node) moveEntities(entities Entities) {
 //
 // returns an error if no entity is found
 func (n *node) remove(entity *Entity) error {
+	if n.tree.looseness > 0 {
+		return n.removeLoose(entity)
+	}
+
 	// check for leaf
 	if len(n.children) > 0 {
 		// find nodes given entity intersects
@@ -261,28 +484,44 @@ func (n *node) remove(entity *Entity) error {
 // collapse collapses a node if the total number of entities from all child nodes is less then or
 // equal to the max number of entities per node.
 func (n *node) collapse() {
-	// create a temp list of entities
-	entities := make(Entities, 0)
+	// create a temp list of entities starting with any entities held by this node in a loose tree
+	entities := append(make(Entities, 0, len(n.entities)), n.entities...)
 
 	// attempted to merge all children entities in to the new entities list
 	// this ignores all duplicate entities
 	for i := range n.children {
+		// a child with children of its own can not be collapsed in to this node
+		if len(n.children[i].children) > 0 {
+			return
+		}
+
 		entities = entities.Merge(n.children[i].entities)
 	}
 
 	// check if the number of entities merged in to new list are less
 	// then the cap of this nodes entities
-	if len(entities) <= cap(n.entities) {
+	if uint64(len(entities)) <= n.tree.maxEntities {
 		// move found entities to this nodes entities
 		n.entities = entities
 
 		// remove children from this node
 		n.children = n.children[:0]
+
+		n.tree.metrics.collapse()
+		n.tree.notify(Event{Type: EventCollapse, Nodes: []pixel.Rect{n.rect}, Entities: append(Entities(nil), entities...)})
 	}
 }
 
 // retrieve gets all entities from all leafs the given pixel.Rect intersects
-func (n *node) retrieve(rect pixel.Rect) (entities Entities) {
+//
+// every node visited is counted in m, which is nil for queries not made through the public API.
+func (n *node) retrieve(rect pixel.Rect, m *Metrics) (entities Entities) {
+	if n.tree.looseness > 0 {
+		return n.retrieveLoose(rect, nil, m)
+	}
+
+	m.visit()
+
 	// check for a leaf node
 	if len(n.children) > 0 {
 		// get all nodes pixel.Rect intersects
@@ -296,7 +535,7 @@ func (n *node) retrieve(rect pixel.Rect) (entities Entities) {
 
 		// recursive retrieve and merge call to add found entities to return list
 		for i := range nodes {
-			entities = entities.Merge(nodes[i].retrieve(rect))
+			entities = entities.Merge(nodes[i].retrieve(rect, m))
 		}
 		return
 	}
@@ -306,7 +545,13 @@ func (n *node) retrieve(rect pixel.Rect) (entities Entities) {
 }
 
 // intersect checks if the given pixel.Rect intersects any entity with in the tree
-func (n *node) intersect(rect pixel.Rect) bool {
+func (n *node) intersect(rect pixel.Rect, m *Metrics) bool {
+	if n.tree.looseness > 0 {
+		return n.intersectLoose(rect, m)
+	}
+
+	m.visit()
+
 	// check for a leaf
 	if len(n.children) > 0 {
 		// get all nodes the given pixel.Rect intersects
@@ -320,7 +565,7 @@ func (n *node) intersect(rect pixel.Rect) bool {
 
 		// check for intersects for all returned nodes
 		for i := range nodes {
-			if nodes[i].intersect(rect) {
+			if nodes[i].intersect(rect, m) {
 				return true
 			}
 		}
@@ -329,11 +574,17 @@ func (n *node) intersect(rect pixel.Rect) bool {
 	}
 
 	// check for intersects with any entity with in this nodes entities
-	return n.entities.Intersect(rect)
+	return n.entities.IntersectWith(rect, n.tree.overlap)
 }
 
 // isEntity checks if a given entity exists with in the tree
-func (n *node) isEntity(entity *Entity) bool {
+func (n *node) isEntity(entity *Entity, m *Metrics) bool {
+	if n.tree.looseness > 0 {
+		return n.isEntityLoose(entity, m)
+	}
+
+	m.visit()
+
 	// check if you are at a leaf
 	if len(n.children) > 0 {
 		// get all nodes the given entity intersects
@@ -347,7 +598,7 @@ func (n *node) isEntity(entity *Entity) bool {
 
 		// recursive check for isEntity for all found nodes
 		for i := range nodes {
-			if nodes[i].isEntity(entity) {
+			if nodes[i].isEntity(entity, m) {
 				return true
 			}
 		}
@@ -359,14 +610,51 @@ func (n *node) isEntity(entity *Entity) bool {
 	return n.entities.Contains(entity)
 }
 
+// all returns every entity within this node and its children.
+//
+// Entities stored in more than one leaf are only returned once.
+func (n *node) all() (entities Entities) {
+	seen := make(map[*Entity]bool)
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, e := range n.entities {
+			if !seen[e] {
+				seen[e] = true
+				entities = append(entities, e)
+			}
+		}
+		for i := range n.children {
+			walk(n.children[i])
+		}
+	}
+	walk(n)
+
+	return
+}
+
 // getQuadrant finds all nodes the given pixel.Rect intersects with
+//
+// Nodes are matched with the tree's placement rules so entities and queries reach the same nodes.
 func (n *node) getQuadrant(rect pixel.Rect) (nodes []*node) {
 	// check each child node for intersect
 	for i := range n.children {
 		// check if the child node rect intersects the given pixel.Rect
-		if n.children[i].rect.Intersects(rect) {
+		if n.tree.overlap.placement().Intersects(n.children[i].rect, rect) {
 			nodes = append(nodes, n.children[i])
 		}
 	}
 	return
 }
+
+// accepts checks if the tree can hold an entity with the given pixel.Rect bounds.
+func (q *Quadpix) accepts(rect pixel.Rect) bool {
+	return q.config.accepts(q.rect, rect)
+}
+
+// reaches checks if a query for the given pixel.Rect reaches the root of the tree.
+//
+// Queries that do not reach the root find no nodes to search, which makes retrieve panic with ErrNoNodeFound.
+func (q *Quadpix) reaches(rect pixel.Rect) bool {
+	return q.overlap.placement().Intersects(q.rect, rect)
+}
@@ -33,7 +33,9 @@ func TestNew(t *testing.T) {
 					children: make([]*node, 0, 4),
 					depth:    0,
 				},
-				maxDepth: 4,
+				config: config{
+					maxDepth: 4,
+				},
 			},
 		},
 	}
@@ -773,3 +775,159 @@ func TestQuadGo_Intersects(t *testing.T) {
 		})
 	}
 }
+
+func TestQuadGo_Update(t *testing.T) {
+	type fields struct {
+		quadpix  *Quadpix
+		entities Entities
+	}
+	type args struct {
+		entity *Entity
+		rect   pixel.Rect
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr error
+	}{
+		{
+			name: "update entity in root",
+			fields: fields{
+				quadpix: New(800, 600, 10, 4),
+				entities: Entities{
+					&Entity{
+						ID:   1,
+						Rect: pixel.R(0, 0, 50, 50),
+					},
+				},
+			},
+			args: args{
+				entity: &Entity{
+					ID:   1,
+					Rect: pixel.R(0, 0, 50, 50),
+				},
+				rect: pixel.R(100, 100, 150, 150),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "update entity across leafs",
+			fields: fields{
+				quadpix: New(800, 600, 1, 4),
+				entities: Entities{
+					&Entity{
+						ID:   1,
+						Rect: pixel.R(0, 0, 50, 50),
+					},
+					&Entity{
+						ID:   2,
+						Rect: pixel.R(500, 400, 550, 450),
+					},
+				},
+			},
+			args: args{
+				entity: &Entity{
+					ID:   1,
+					Rect: pixel.R(0, 0, 50, 50),
+				},
+				rect: pixel.R(600, 500, 650, 550),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "update non entity error",
+			fields: fields{
+				quadpix: New(800, 600, 10, 4),
+				entities: Entities{
+					E(pixel.R(0, 0, 50, 50)),
+				},
+			},
+			args: args{
+				entity: E(pixel.R(10, 10, 50, 50)),
+				rect:   pixel.R(100, 100, 150, 150),
+			},
+			wantErr: ErrNoEntityFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fields.quadpix.InsertEntities(tt.fields.entities...)
+			if err != nil {
+				t.Errorf("QuadGo.Update() got error on insert %v", err)
+			}
+
+			old := tt.args.entity.Rect
+
+			err = tt.fields.quadpix.Update(tt.args.entity, tt.args.rect)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("QuadGo.Update() got an unwanted error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !<-tt.fields.quadpix.IsEntity(tt.args.entity) {
+				t.Errorf("QuadGo.Update() entity not found at new bounds %v", tt.args.rect)
+			}
+
+			if <-tt.fields.quadpix.IsEntity(&Entity{ID: tt.args.entity.ID, Rect: old}) {
+				t.Errorf("QuadGo.Update() entity still found at old bounds %v", old)
+			}
+		})
+	}
+}
+
+func TestQuadGo_SplitRule(t *testing.T) {
+	q := New(100, 100, 2, 4)
+	a := E(pixel.R(10, 10, 11, 11))
+	b := E(pixel.R(60, 10, 61, 11))
+	c := E(pixel.R(10, 60, 11, 61))
+	if err := q.InsertEntities(a, b, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// collapsing the root builds its list of entities with append, which can leave it with
+	// room for more then maxEntities entities
+	if err := q.Remove(c); err != nil {
+		t.Fatal(err)
+	}
+	q.entities = append(make(Entities, 0, 8), q.entities...)
+
+	q.Insert(pixel.R(60, 60, 61, 61))
+	q.Insert(pixel.R(80, 80, 81, 81))
+
+	if len(q.children) == 0 {
+		t.Errorf("tree did not split at maxEntities, root holds %d entities", len(q.entities))
+	}
+}
+
+func TestQuadGo_CollapseKeepsGrandchildren(t *testing.T) {
+	q := New(100, 100, 2, 4)
+	// four entities in the bottom left quadrant split it again
+	entities := Entities{
+		E(pixel.R(1, 1, 2, 2)),
+		E(pixel.R(30, 1, 31, 2)),
+		E(pixel.R(1, 30, 2, 31)),
+		E(pixel.R(80, 80, 81, 81)),
+		E(pixel.R(30, 30, 31, 31)),
+	}
+	if err := q.InsertEntities(entities...); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.children) != 4 || len(q.children[0].children) != 4 {
+		t.Fatalf("tree was not split twice")
+	}
+
+	// removing from the root tries to collapse it, which must not drop the split child
+	if err := q.Remove(entities[3]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range (Entities{entities[0], entities[1], entities[2], entities[4]}) {
+		if !<-q.IsEntity(e) {
+			t.Errorf("entity %v lost after collapse", e)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package quadpix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/faiface/pixel"
+)
+
+// Recorder writes every Insert, Remove, Update and query done on a tree to a log which can be run again with Replay.
+//
+// The log is written as one JSON object per line. It starts with a binary snapshot of the tree, including its
+// node structure, followed by one line per operation. Mutations are logged with their arguments and queries are
+// logged with their arguments and results. Entities are referenced by their order in the log, not by their ID,
+// and their Actions and Behaviours are not recorded.
+//
+// Queries are recorded from the threads they run on, so recording is safe while read operations are running.
+type Recorder struct {
+	tree *Quadpix
+
+	mu   sync.Mutex
+	w    io.Writer
+	refs map[*Entity]uint64
+	next uint64
+	err  error
+}
+
+// event is a single line of a recorded log.
+type event struct {
+	Op string `json:"op"`
+
+	// Tree is the binary snapshot of the tree for a start event.
+	Tree []byte `json:"tree,omitempty"`
+
+	// Entity is the log reference of the entity used by a mutation, with its ID and bounds before and after.
+	Entity uint64    `json:"entity,omitempty"`
+	ID     uint64    `json:"id,omitempty"`
+	From   *rectJSON `json:"from,omitempty"`
+	To     *rectJSON `json:"to,omitempty"`
+
+	// Rect is the bounds of a query, with the references of the entities it returned or whether it hit.
+	Rect   *rectJSON `json:"rect,omitempty"`
+	Result []uint64  `json:"result,omitempty"`
+	Hit    bool      `json:"hit,omitempty"`
+}
+
+// names of the recorded events that are not mutations.
+const (
+	eventStart      = "start"
+	eventRetrieve   = "retrieve"
+	eventIntersects = "intersects"
+	eventIntersect  = "intersect"
+	eventIsEntity   = "isEntity"
+)
+
+// StartRecording starts writing all operations done on the tree to w and returns the Recorder.
+//
+// A snapshot of the tree is written first. If recording has already started it is stopped and a new log is started.
+// StartRecording returns an error if the snapshot could not be written, in which case nothing is recorded.
+func (q *Quadpix) StartRecording(w io.Writer) (*Recorder, error) {
+	r := &Recorder{
+		tree: q,
+		w:    w,
+	}
+
+	r.start()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	q.recorder = r
+
+	return r, nil
+}
+
+// StopRecording stops writing operations to the current log.
+func (q *Quadpix) StopRecording() {
+	q.recorder = nil
+}
+
+// Recorder returns the tree's current Recorder or nil if the tree is not being recorded.
+func (q *Quadpix) Recorder() *Recorder {
+	return q.recorder
+}
+
+// Err returns the first error hit while writing the log.
+//
+// After an error no more operations are written.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// start writes a snapshot of the tree and gives every entity in it a new reference.
+//
+// It is also used when the tree has been replaced, such as after a failed transaction or a load.
+func (r *Recorder) start() {
+	if r == nil {
+		return
+	}
+
+	// the snapshot does not share entities with the tree and its actions and behaviours are
+	// dropped so the log can be replayed without the game's actions being registered.
+	s := r.tree.snapshot()
+	for _, e := range s.all() {
+		e.Actions, e.Behaviours = nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteBinary(&buf, true); err != nil {
+		r.mu.Lock()
+		r.err = err
+		r.mu.Unlock()
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// references follow the order of the entities in the snapshot
+	r.refs = make(map[*Entity]uint64)
+	r.next = 0
+	for _, e := range r.tree.all() {
+		r.ref(e)
+	}
+
+	r.write(event{Op: eventStart, Tree: buf.Bytes()})
+}
+
+// mutation writes the given operation done on the tree.
+func (r *Recorder) mutation(rec record) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ev := event{
+		Op:     rec.op.String(),
+		Entity: r.ref(rec.entity),
+		ID:     rec.entity.ID,
+	}
+	if rec.op != OpInsert {
+		from := toRectJSON(rec.from)
+		ev.From = &from
+	}
+	if rec.op != OpRemove {
+		to := toRectJSON(rec.to)
+		ev.To = &to
+	}
+
+	r.write(ev)
+
+	// a removed entity is given a new reference if it is inserted again
+	if rec.op == OpRemove {
+		delete(r.refs, rec.entity)
+	}
+}
+
+// query writes a query of the given kind and the entities it returned.
+func (r *Recorder) query(op string, rect pixel.Rect, entities Entities) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bounds := toRectJSON(rect)
+	r.write(event{Op: op, Rect: &bounds, Result: r.results(entities)})
+}
+
+// hit writes a query of the given kind that returned whether it hit.
+func (r *Recorder) hit(op string, rect pixel.Rect, id uint64, hit bool) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bounds := toRectJSON(rect)
+	r.write(event{Op: op, Rect: &bounds, ID: id, Hit: hit})
+}
+
+// ref returns the log reference of the given entity, giving it a new one if it has none.
+func (r *Recorder) ref(e *Entity) uint64 {
+	ref, ok := r.refs[e]
+	if !ok {
+		r.next++
+		ref = r.next
+		r.refs[e] = ref
+	}
+	return ref
+}
+
+// results returns the sorted references of the given entities.
+func (r *Recorder) results(entities Entities) []uint64 {
+	refs := make([]uint64, len(entities))
+	for i, e := range entities {
+		refs[i] = r.ref(e)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	return refs
+}
+
+// write writes a single line to the log unless writing has already failed.
+func (r *Recorder) write(ev event) {
+	if r.err != nil {
+		return
+	}
+
+	line, err := json.Marshal(ev)
+	if err != nil {
+		r.err = err
+		return
+	}
+
+	_, r.err = r.w.Write(append(line, '\n'))
+}
+
+// ReplayError is the error returned by Replay for the first operation that failed or did not
+// give the recorded result.
+//
+// Line is the line of the log the operation was read from, starting at 1.
+type ReplayError struct {
+	Line int
+	Op   string
+	Err  error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("replay line %d (%v): %v", e.Line, e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error of the operation.
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+// Replay runs every operation in a log written by a Recorder against a new tree and checks that each query
+// gives the same result it did when it was recorded.
+//
+// Replay returns the tree as it is after the last operation. A last line cut short, as is left behind when
+// the recording program crashed, ends the log. Any other error is returned as a *ReplayError, with an error
+// wrapping ErrReplayMismatch when a query result is different from the recording.
+func Replay(r io.Reader) (*Quadpix, error) {
+	p := &replayer{}
+	br := bufio.NewReader(r)
+
+	for line := 1; ; line++ {
+		data, readErr := br.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return p.tree, &ReplayError{Line: line, Err: readErr}
+		}
+
+		if len(bytes.TrimSpace(data)) > 0 {
+			var ev event
+			if err := json.Unmarshal(data, &ev); err != nil {
+				// a line without its newline was cut short while being written
+				if readErr == io.EOF {
+					break
+				}
+				return p.tree, &ReplayError{Line: line, Err: err}
+			}
+
+			if err := p.run(ev); err != nil {
+				return p.tree, &ReplayError{Line: line, Op: ev.Op, Err: err}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if p.tree == nil {
+		return nil, &ReplayError{Line: 1, Err: fmt.Errorf("%w: log does not start with a snapshot", ErrInvalidFormat)}
+	}
+
+	return p.tree, nil
+}
+
+// replayer holds the state of a running Replay.
+type replayer struct {
+	tree     *Quadpix
+	entities map[uint64]*Entity
+	refs     map[*Entity]uint64
+}
+
+// run runs a single event of the log.
+func (p *replayer) run(ev event) error {
+	if ev.Op == eventStart {
+		return p.start(ev.Tree)
+	}
+	if p.tree == nil {
+		return fmt.Errorf("%w: log does not start with a snapshot", ErrInvalidFormat)
+	}
+
+	switch ev.Op {
+	case OpInsert.String():
+		if ev.To == nil {
+			return fmt.Errorf("%w: insert without bounds", ErrInvalidFormat)
+		}
+		e := p.entity(ev.Entity, ev.ID, ev.To.rect())
+		e.Rect = ev.To.rect()
+		return p.tree.InsertEntities(e)
+	case OpRemove.String():
+		if ev.From == nil {
+			return fmt.Errorf("%w: remove without bounds", ErrInvalidFormat)
+		}
+		e := p.entity(ev.Entity, ev.ID, ev.From.rect())
+		if err := p.tree.Remove(e); err != nil {
+			return err
+		}
+		delete(p.entities, ev.Entity)
+		delete(p.refs, e)
+		return nil
+	case OpUpdate.String():
+		if ev.From == nil || ev.To == nil {
+			return fmt.Errorf("%w: update without bounds", ErrInvalidFormat)
+		}
+		return p.tree.Update(p.entity(ev.Entity, ev.ID, ev.From.rect()), ev.To.rect())
+	case eventRetrieve, eventIntersects:
+		if ev.Rect == nil {
+			return fmt.Errorf("%w: query without bounds", ErrInvalidFormat)
+		}
+		var entities Entities
+		if ev.Op == eventRetrieve {
+			entities = <-p.tree.Retrieve(ev.Rect.rect())
+		} else {
+			entities = <-p.tree.Intersects(ev.Rect.rect())
+		}
+		return p.compare(entities, ev.Result)
+	case eventIntersect, eventIsEntity:
+		if ev.Rect == nil {
+			return fmt.Errorf("%w: query without bounds", ErrInvalidFormat)
+		}
+		var hit bool
+		if ev.Op == eventIntersect {
+			hit = <-p.tree.Intersect(ev.Rect.rect())
+		} else {
+			hit = <-p.tree.IsEntity(&Entity{ID: ev.ID, Rect: ev.Rect.rect()})
+		}
+		if hit != ev.Hit {
+			return fmt.Errorf("%w: got %v, want %v", ErrReplayMismatch, hit, ev.Hit)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrInvalidFormat, ev.Op)
+	}
+}
+
+// start replaces the tree with the given snapshot.
+func (p *replayer) start(snapshot []byte) error {
+	tree := &Quadpix{}
+	if err := tree.UnmarshalBinary(snapshot); err != nil {
+		return err
+	}
+
+	p.tree = tree
+	p.entities = make(map[uint64]*Entity)
+	p.refs = make(map[*Entity]uint64)
+	// references follow the order of the entities in the snapshot
+	for i, e := range tree.all() {
+		ref := uint64(i + 1)
+		p.entities[ref] = e
+		p.refs[e] = ref
+	}
+
+	return nil
+}
+
+// entity returns the entity with the given log reference, creating it if it has not been seen yet.
+func (p *replayer) entity(ref, id uint64, rect pixel.Rect) *Entity {
+	e, ok := p.entities[ref]
+	if !ok {
+		e = &Entity{ID: id, Rect: rect}
+		p.entities[ref] = e
+		p.refs[e] = ref
+	}
+	return e
+}
+
+// compare checks that the given entities are the entities with the given log references.
+func (p *replayer) compare(entities Entities, want []uint64) error {
+	got := make([]uint64, len(entities))
+	for i, e := range entities {
+		got[i] = p.refs[e]
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+
+	if len(got) != len(want) {
+		return fmt.Errorf("%w: got entities %v, want %v", ErrReplayMismatch, got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return fmt.Errorf("%w: got entities %v, want %v", ErrReplayMismatch, got, want)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package quadpix
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+// recordSession runs a mix of operations on a recorded tree and returns the log.
+func recordSession(t *testing.T) []byte {
+	t.Helper()
+
+	tree := New(800, 600, 2, 4)
+	a := &Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)}
+	tree.InsertEntities(a)
+
+	var log bytes.Buffer
+	if _, err := tree.StartRecording(&log); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)}
+	c := &Entity{ID: 3, Rect: pixel.R(350, 250, 450, 350)}
+	tree.InsertEntities(b, c)
+	tree.Insert(pixel.R(700, 10, 750, 60))
+	<-tree.Retrieve(pixel.R(0, 0, 100, 100))
+	tree.Update(b, pixel.R(600, 400, 650, 450))
+	<-tree.Intersects(pixel.R(300, 200, 700, 500))
+	<-tree.Intersect(pixel.R(200, 500, 250, 550))
+	<-tree.IsEntity(c)
+
+	// a failed transaction restores the tree
+	tx := tree.Begin()
+	tx.Remove(c)
+	tx.Remove(c)
+	if err := tx.Commit(); err == nil {
+		t.Fatal("Tx.Commit() returned no error")
+	}
+
+	// removing by an equal entity rather then the stored one
+	tree.Remove(&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)})
+	<-tree.Retrieve(pixel.R(0, 0, 800, 600))
+
+	if err := tree.Recorder().Err(); err != nil {
+		t.Fatal(err)
+	}
+	tree.StopRecording()
+	tree.Insert(pixel.R(0, 0, 10, 10))
+
+	return log.Bytes()
+}
+
+func TestReplay(t *testing.T) {
+	log := recordSession(t)
+
+	tree, err := Replay(bytes.NewReader(log))
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	// the insert done after recording stopped is not in the log
+	if got := len(tree.all()); got != 3 {
+		t.Errorf("Replay() tree has %v entities, want %v", got, 3)
+	}
+	if err := tree.validate(); err != nil {
+		t.Errorf("Replay() tree is invalid: %v", err)
+	}
+}
+
+func TestReplay_errors(t *testing.T) {
+	log := string(recordSession(t))
+	lines := strings.SplitAfter(log, "\n")
+
+	tests := []struct {
+		name    string
+		log     string
+		wantErr error
+		wantNil bool
+	}{
+		{
+			name: "cut short last line",
+			log:  log[:len(log)-10],
+		},
+		{
+			name:    "changed result",
+			log:     strings.Replace(log, `"op":"intersect","rect":{"min":{"x":200,"y":500},"max":{"x":250,"y":550}}`, `"op":"intersect","rect":{"min":{"x":200,"y":500},"max":{"x":250,"y":550}},"hit":true`, 1),
+			wantErr: ErrReplayMismatch,
+		},
+		{
+			name:    "missing entity",
+			log:     strings.Replace(log, `"result":[1,3]`, `"result":[1]`, 1),
+			wantErr: ErrReplayMismatch,
+		},
+		{
+			name:    "no snapshot",
+			log:     strings.Join(lines[1:3], ""),
+			wantErr: ErrInvalidFormat,
+			wantNil: true,
+		},
+		{
+			name:    "empty log",
+			log:     "",
+			wantErr: ErrInvalidFormat,
+			wantNil: true,
+		},
+		{
+			name:    "unknown operation",
+			log:     lines[0] + `{"op":"explode"}` + "\n",
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "removing a missing entity",
+			log:     lines[0] + `{"op":"remove","entity":9,"id":9,"from":{"min":{"x":1,"y":1},"max":{"x":2,"y":2}}}` + "\n",
+			wantErr: ErrNoEntityFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := Replay(strings.NewReader(tt.log))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("Replay() error = %v, want %v", err, tt.wantErr)
+			}
+			var replayErr *ReplayError
+			if err != nil && !errors.As(err, &replayErr) {
+				t.Errorf("Replay() error = %T, want *ReplayError", err)
+			}
+			if (tree == nil) != tt.wantNil {
+				t.Errorf("Replay() tree = %v, want nil %v", tree, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestRecorder_removeForgetsEntity(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+
+	var log bytes.Buffer
+	r, err := tree.StartRecording(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		e := &Entity{ID: uint64(i), Rect: pixel.R(10, 10, 50, 50)}
+		tree.InsertEntities(e)
+		tree.Remove(e)
+	}
+	if len(r.refs) != 0 {
+		t.Fatalf("Recorder holds %v references to removed entities, want 0", len(r.refs))
+	}
+
+	// an entity inserted again after it was removed is replayed as a new entity
+	a := &Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)}
+	tree.InsertEntities(a)
+	tree.Remove(a)
+	tree.InsertEntities(a)
+	<-tree.Retrieve(pixel.R(0, 0, 100, 100))
+	tree.Update(a, pixel.R(500, 400, 550, 450))
+	<-tree.Retrieve(pixel.R(400, 300, 600, 500))
+
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := Replay(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if got := len(replayed.all()); got != 1 {
+		t.Errorf("Replay() tree has %v entities, want %v", got, 1)
+	}
+}
@@ -0,0 +1,282 @@
+package quadpix
+
+import (
+	"math"
+	"sort"
+
+	"github.com/faiface/pixel"
+)
+
+// RTree is a static R-tree packed with Sort-Tile-Recursive bulk loading.
+//
+// An RTree is built once from a list of entities that never move, such as the walls and terrain of a level.
+// Packing groups nearby entities in to full nodes with tight bounds, so queries visit fewer nodes then they would
+// with the fixed quadrants of a quadtree. The nodes are stored in one flat list to keep queries cache friendly.
+//
+// An RTree can not be changed after it is built. It implements SpatialQuery and follows the same rules as Quadpix.
+type RTree struct {
+	overlap Overlap
+
+	// entities holds every entity in the order of the leafs they are packed in to.
+	entities Entities
+
+	// nodes holds every node one level at a time from the leafs up, with the root last.
+	nodes  []rtreeNode
+	height int
+}
+
+// rtreeNode is a node of an RTree holding a range of nodes of the level below, or of entities for a leaf.
+type rtreeNode struct {
+	bounds pixel.Rect
+	first  int
+	count  int
+	leaf   bool
+}
+
+// rtreeFanout is the number of entries packed in to each node of an RTree.
+const rtreeFanout = 16
+
+var _ SpatialQuery = (*RTree)(nil)
+
+// NewRTree builds a new RTree holding the given entities.
+//
+// The given list is not changed. The Edges and Epsilon Options set how the RTree's queries treat touching
+// entities and all other Options are ignored.
+func NewRTree(entities Entities, opts ...Option) *RTree {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	t := &RTree{
+		overlap:  cfg.overlap,
+		entities: append(Entities(nil), entities...),
+	}
+	t.build()
+
+	return t
+}
+
+// Len returns the number of entities in the RTree.
+func (t *RTree) Len() int {
+	return len(t.entities)
+}
+
+// Height returns the number of levels of nodes in the RTree.
+func (t *RTree) Height() int {
+	return t.height
+}
+
+// Bounds returns the bounds of all entities in the RTree.
+func (t *RTree) Bounds() pixel.Rect {
+	if len(t.nodes) == 0 {
+		return pixel.Rect{}
+	}
+	return t.nodes[len(t.nodes)-1].bounds
+}
+
+// Retrieve gets all entities in the leafs the given pixel.Rect reaches.
+//
+// Retrieve returns a channel of entities as all Read-Only operations are run on there own thread.
+func (t *RTree) Retrieve(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		var entities Entities
+		t.query(rect, func(found Entities) bool {
+			entities = append(entities, found...)
+			return true
+		})
+
+		out <- entities
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersect returns whether or not the given pixel.Rect intersects any entity with in the RTree.
+//
+// Intersect returns a channel of a bool as all Read-Only operations are run on there own thread.
+func (t *RTree) Intersect(rect pixel.Rect) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		hit := false
+		t.query(rect, func(found Entities) bool {
+			hit = found.IntersectWith(rect, t.overlap)
+			return !hit
+		})
+
+		out <- hit
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersects returns a channel of all entities that intersect with the given pixel.Rect within the RTree.
+//
+// Intersects returns a channel of Entities as all Read-Only operations are run on there own thread.
+func (t *RTree) Intersects(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		var entities Entities
+		t.query(rect, func(found Entities) bool {
+			entities = append(entities, found.IntersectsWith(rect, t.overlap)...)
+			return true
+		})
+
+		out <- entities
+		close(out)
+	}()
+
+	return out
+}
+
+// IsEntity returns whether or not the given entity exists with in the RTree.
+//
+// IsEntity returns a channel holding a bool as all Read-Only operations are run on there own thread.
+// The given entity must have the same ID and pixel.Rect bounds to be found.
+func (t *RTree) IsEntity(entity *Entity) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		found := false
+		t.query(entity.Rect, func(entities Entities) bool {
+			found = entities.Contains(entity)
+			return !found
+		})
+
+		out <- found
+		close(out)
+	}()
+
+	return out
+}
+
+// query calls fn with the entities of every leaf the given pixel.Rect reaches until fn returns false.
+func (t *RTree) query(rect pixel.Rect, fn func(entities Entities) bool) {
+	if len(t.nodes) == 0 {
+		return
+	}
+
+	// node bounds are matched the same way a tree matches nodes so touching entities are never missed
+	o := t.overlap.placement()
+
+	stack := []int{len(t.nodes) - 1}
+	for len(stack) > 0 {
+		n := &t.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+
+		if !o.Intersects(n.bounds, rect) {
+			continue
+		}
+
+		if n.leaf {
+			if !fn(t.entities[n.first : n.first+n.count]) {
+				return
+			}
+			continue
+		}
+
+		for i := n.first; i < n.first+n.count; i++ {
+			stack = append(stack, i)
+		}
+	}
+}
+
+// build packs the entities in to leafs and then packs each level of nodes in to the level above until one node is left.
+func (t *RTree) build() {
+	if len(t.entities) == 0 {
+		return
+	}
+
+	// pack the entities in to leafs
+	strSort(len(t.entities), func(i int) pixel.Vec { return t.entities[i].Rect.Center() }, func(i, j int) {
+		t.entities[i], t.entities[j] = t.entities[j], t.entities[i]
+	})
+	for first := 0; first < len(t.entities); first += rtreeFanout {
+		count := minInt(rtreeFanout, len(t.entities)-first)
+
+		bounds := t.entities[first].Rect
+		for _, e := range t.entities[first+1 : first+count] {
+			bounds = bounds.Union(e.Rect)
+		}
+
+		t.nodes = append(t.nodes, rtreeNode{bounds: bounds, first: first, count: count, leaf: true})
+	}
+	t.height = 1
+
+	// pack each level in to the level above
+	level := 0
+	for len(t.nodes)-level > 1 {
+		nodes := t.nodes[level:]
+		strSort(len(nodes), func(i int) pixel.Vec { return nodes[i].bounds.Center() }, func(i, j int) {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		})
+
+		next := len(t.nodes)
+		for first := level; first < next; first += rtreeFanout {
+			count := minInt(rtreeFanout, next-first)
+
+			bounds := t.nodes[first].bounds
+			for _, n := range t.nodes[first+1 : first+count] {
+				bounds = bounds.Union(n.bounds)
+			}
+
+			t.nodes = append(t.nodes, rtreeNode{bounds: bounds, first: first, count: count})
+		}
+
+		level = next
+		t.height++
+	}
+}
+
+// strSort orders n items for Sort-Tile-Recursive packing by the given centers.
+//
+// The items are sorted by x and cut in to vertical slices of whole nodes, then each slice is sorted by y,
+// so each run of rtreeFanout items covers a small tile.
+func strSort(n int, center func(i int) pixel.Vec, swap func(i, j int)) {
+	sort.Sort(strSorter{n: n, center: center, swap: swap, x: true})
+
+	nodes := (n + rtreeFanout - 1) / rtreeFanout
+	slices := int(math.Ceil(math.Sqrt(float64(nodes))))
+	size := slices * rtreeFanout
+
+	for first := 0; first < n; first += size {
+		count := minInt(size, n-first)
+		sort.Sort(strSorter{
+			n:      count,
+			center: func(i int) pixel.Vec { return center(first + i) },
+			swap:   func(i, j int) { swap(first+i, first+j) },
+		})
+	}
+}
+
+// strSorter sorts items by the x or y of there centers.
+type strSorter struct {
+	n      int
+	center func(i int) pixel.Vec
+	swap   func(i, j int)
+	x      bool
+}
+
+func (s strSorter) Len() int { return s.n }
+
+func (s strSorter) Less(i, j int) bool {
+	if s.x {
+		return s.center(i).X < s.center(j).X
+	}
+	return s.center(i).Y < s.center(j).Y
+}
+
+func (s strSorter) Swap(i, j int) { s.swap(i, j) }
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,124 @@
+package quadpix
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestNewRTree(t *testing.T) {
+	tests := []struct {
+		name       string
+		entities   int
+		wantHeight int
+	}{
+		{"empty", 0, 0},
+		{"one", 1, 1},
+		{"one full leaf", rtreeFanout, 1},
+		{"two leafs", rtreeFanout + 1, 2},
+		{"three levels", rtreeFanout*rtreeFanout + 1, 3},
+		{"many", 10000, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			entities := make(Entities, tt.entities)
+			for i := range entities {
+				entities[i] = &Entity{ID: uint64(i + 1), Rect: randomRect(r, 20)}
+			}
+			input := append(Entities(nil), entities...)
+
+			tree := NewRTree(entities)
+
+			if got := tree.Height(); got != tt.wantHeight {
+				t.Errorf("RTree.Height() = %v, want %v", got, tt.wantHeight)
+			}
+			if got := tree.Len(); got != tt.entities {
+				t.Errorf("RTree.Len() = %v, want %v", got, tt.entities)
+			}
+			for i := range entities {
+				if entities[i] != input[i] {
+					t.Fatalf("NewRTree() changed the order of the given entities")
+				}
+			}
+
+			// every entity is packed once
+			seen := make(map[*Entity]bool)
+			for _, e := range tree.entities {
+				if seen[e] {
+					t.Fatalf("entity %v packed more then once", e.ID)
+				}
+				seen[e] = true
+			}
+			if len(seen) != len(entities) {
+				t.Fatalf("%v entities packed, want %v", len(seen), len(entities))
+			}
+
+			// every node has tight bounds and at most one node of each level is not full
+			var level []int
+			if len(tree.nodes) > 0 {
+				level = []int{len(tree.nodes) - 1}
+			}
+			for len(level) > 0 {
+				var next []int
+				partial := 0
+				for _, index := range level {
+					n := tree.nodes[index]
+					if n.count != rtreeFanout {
+						partial++
+					}
+
+					bounds := pixel.Rect{Min: pixel.V(math.Inf(1), math.Inf(1)), Max: pixel.V(math.Inf(-1), math.Inf(-1))}
+					for j := n.first; j < n.first+n.count; j++ {
+						if n.leaf {
+							bounds = bounds.Union(tree.entities[j].Rect)
+						} else {
+							bounds = bounds.Union(tree.nodes[j].bounds)
+							next = append(next, j)
+						}
+					}
+					if bounds != n.bounds {
+						t.Fatalf("node %v bounds %v, want %v", index, n.bounds, bounds)
+					}
+				}
+				if partial > 1 {
+					t.Fatalf("level with %v nodes has %v nodes that are not full, want at most 1", len(level), partial)
+				}
+				level = next
+			}
+		})
+	}
+}
+
+func TestRTree_fewerVisits(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	entities := make(Entities, 5000)
+	for i := range entities {
+		entities[i] = &Entity{ID: uint64(i + 1), Rect: randomRect(r, 20)}
+	}
+
+	rtree := NewRTree(entities)
+	tree := New(1000, 1000, rtreeFanout, 8)
+	tree.InsertEntities(entities...)
+
+	// count the nodes each structure reaches for the same queries
+	var rtreeNodes, treeNodes int
+	for i := 0; i < 200; i++ {
+		query := randomRect(r, 100)
+
+		// every node a query reaches intersects it, as a parent's bounds always hold its children
+		for _, n := range rtree.nodes {
+			if n.bounds.Intersects(query) {
+				rtreeNodes++
+			}
+		}
+		treeNodes += len((<-tree.Explain(query)).Nodes)
+	}
+
+	if rtreeNodes >= treeNodes {
+		t.Errorf("RTree reached %v nodes, want fewer then the %v reached by Quadpix", rtreeNodes, treeNodes)
+	}
+}
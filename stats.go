@@ -0,0 +1,234 @@
+package quadpix
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+)
+
+// NodeInfo describes a single node of the tree.
+type NodeInfo struct {
+	Bounds   pixel.Rect
+	Depth    int
+	Entities int
+	Leaf     bool
+}
+
+// info returns the NodeInfo of this node.
+func (n *node) info() NodeInfo {
+	return NodeInfo{
+		Bounds:   n.rect,
+		Depth:    int(n.depth),
+		Entities: len(n.entities),
+		Leaf:     len(n.children) == 0,
+	}
+}
+
+// Stats holds the shape and size of a tree.
+type Stats struct {
+	Bounds      pixel.Rect
+	MaxEntities uint64
+	MaxDepth    uint16
+
+	// Entities is the number of unique entities in the tree and Stored the number of entities
+	// stored in all nodes, counting an entity once for each node it is stored in.
+	Entities int
+	Stored   int
+
+	Nodes  int
+	Leaves int
+	Depth  int
+
+	// EmptyLeaves is the number of leafs with no entities and FullLeaves the number of leafs
+	// holding more then MaxEntities entities.
+	EmptyLeaves int
+	FullLeaves  int
+
+	// MaxLeafEntities is the largest number of entities stored in one leaf.
+	MaxLeafEntities int
+}
+
+// Duplication returns the average number of nodes each entity is stored in.
+func (s Stats) Duplication() float64 {
+	if s.Entities == 0 {
+		return 0
+	}
+	return float64(s.Stored) / float64(s.Entities)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("bounds: %v\nmax entities: %d\nmax depth: %d\nentities: %d\nstored: %d (%.2fx)\nnodes: %d\nleaves: %d (empty %d, over max %d)\ndepth: %d\nmax leaf entities: %d\n",
+		s.Bounds, s.MaxEntities, s.MaxDepth, s.Entities, s.Stored, s.Duplication(),
+		s.Nodes, s.Leaves, s.EmptyLeaves, s.FullLeaves, s.Depth, s.MaxLeafEntities)
+}
+
+// Stats returns the Stats of the tree.
+//
+// Stats returns a channel of Stats. This is due to the fact that all Read-Only operations in Quadpix are run on there own thread.
+func (q *Quadpix) Stats() <-chan Stats {
+	out := make(chan Stats)
+
+	go func() {
+		out <- q.stats()
+		close(out)
+	}()
+
+	return out
+}
+
+// Nodes returns the NodeInfo of every node in the tree in pre-order.
+//
+// Nodes returns a channel of NodeInfo's. This is due to the fact that all Read-Only operations in Quadpix are run on there own thread.
+func (q *Quadpix) Nodes() <-chan []NodeInfo {
+	out := make(chan []NodeInfo)
+
+	go func() {
+		var nodes []NodeInfo
+		q.walk(func(n *node) {
+			nodes = append(nodes, n.info())
+		})
+
+		out <- nodes
+		close(out)
+	}()
+
+	return out
+}
+
+// Validate checks the structure of the tree.
+//
+// Validate checks that every node has 0 or 4 children covering its quadrants, no node is deeper then the
+// max depth, only leafs hold entities, every entity has valid bounds and every entity is stored in each
+// leaf it intersects and no others. It returns an error wrapping ErrInvalidTree for the first problem found.
+//
+// Validate returns a channel of an error. This is due to the fact that all Read-Only operations in Quadpix are run on there own thread.
+func (q *Quadpix) Validate() <-chan error {
+	out := make(chan error)
+
+	go func() {
+		out <- q.validate()
+		close(out)
+	}()
+
+	return out
+}
+
+// stats collects the Stats of the tree.
+func (q *Quadpix) stats() Stats {
+	s := Stats{
+		Bounds:      q.rect,
+		MaxEntities: q.maxEntities,
+		MaxDepth:    q.maxDepth,
+		Entities:    len(q.all()),
+	}
+
+	q.walk(func(n *node) {
+		s.Nodes++
+		s.Stored += len(n.entities)
+
+		if int(n.depth) > s.Depth {
+			s.Depth = int(n.depth)
+		}
+
+		if len(n.children) > 0 {
+			return
+		}
+
+		s.Leaves++
+		switch {
+		case len(n.entities) == 0:
+			s.EmptyLeaves++
+		case uint64(len(n.entities)) > q.maxEntities:
+			s.FullLeaves++
+		}
+		if len(n.entities) > s.MaxLeafEntities {
+			s.MaxLeafEntities = len(n.entities)
+		}
+	})
+
+	return s
+}
+
+// validate checks the structure of the tree.
+func (q *Quadpix) validate() error {
+	var check func(n *node) error
+	check = func(n *node) error {
+		if n.depth > q.maxDepth {
+			return fmt.Errorf("%w: node %v deeper then max depth %d", ErrInvalidTree, n.rect, q.maxDepth)
+		}
+
+		for i, e := range n.entities {
+			if !validRect(e.Rect) {
+				return fmt.Errorf("%w: entity %v has invalid bounds %v", ErrInvalidTree, e.ID, e.Rect)
+			}
+			if !n.holds(e.Rect) {
+				return fmt.Errorf("%w: entity %v stored in node %v that can not hold it", ErrInvalidTree, e.ID, n.rect)
+			}
+			if n.entities[:i].has(e) {
+				return fmt.Errorf("%w: entity %v stored twice in node %v", ErrInvalidTree, e.ID, n.rect)
+			}
+		}
+
+		switch len(n.children) {
+		case 0:
+			return nil
+		case 4:
+		default:
+			return fmt.Errorf("%w: node %v has %d children", ErrInvalidTree, n.rect, len(n.children))
+		}
+
+		if len(n.entities) > 0 && q.looseness == 0 {
+			return fmt.Errorf("%w: branch node %v holds entities", ErrInvalidTree, n.rect)
+		}
+
+		c := n.rect.Center()
+		quadrants := [4]pixel.Rect{
+			pixel.R(n.rect.Min.X, n.rect.Min.Y, c.X, c.Y),
+			pixel.R(c.X, n.rect.Min.Y, n.rect.Max.X, c.Y),
+			pixel.R(n.rect.Min.X, c.Y, c.X, n.rect.Max.Y),
+			pixel.R(c.X, c.Y, n.rect.Max.X, n.rect.Max.Y),
+		}
+		for i, child := range n.children {
+			if child.rect != quadrants[i] || child.depth != n.depth+1 {
+				return fmt.Errorf("%w: node %v child %d has bounds %v at depth %d", ErrInvalidTree, n.rect, i, child.rect, child.depth)
+			}
+			if err := check(child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := check(q.node); err != nil {
+		return err
+	}
+
+	entities := q.all()
+	for _, e := range entities {
+		if !q.placed(e) {
+			return fmt.Errorf("%w: entity %v missing from a node it belongs in", ErrInvalidTree, e.ID)
+		}
+	}
+
+	// a loose tree stores each entity once
+	if q.looseness > 0 {
+		if stored := q.stats().Stored; stored != len(entities) {
+			return fmt.Errorf("%w: %d entities stored %d times in a loose tree", ErrInvalidTree, len(entities), stored)
+		}
+	}
+
+	return nil
+}
+
+// walk calls fn for every node of the tree in pre-order.
+func (q *Quadpix) walk(fn func(n *node)) {
+	var walk func(n *node)
+	walk = func(n *node) {
+		fn(n)
+		for i := range n.children {
+			walk(n.children[i])
+		}
+	}
+	walk(q.node)
+}
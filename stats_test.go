@@ -0,0 +1,107 @@
+package quadpix
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestQuadpix_Stats(t *testing.T) {
+	tree := New(800, 600, 2, 4)
+	tree.InsertEntities(
+		&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+		&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+		&Entity{ID: 3, Rect: pixel.R(350, 250, 450, 350)},
+	)
+
+	got := <-tree.Stats()
+	want := Stats{
+		Bounds:          pixel.R(0, 0, 800, 600),
+		MaxEntities:     2,
+		MaxDepth:        4,
+		Entities:        3,
+		Stored:          6,
+		Nodes:           5,
+		Leaves:          4,
+		Depth:           1,
+		EmptyLeaves:     0,
+		FullLeaves:      0,
+		MaxLeafEntities: 2,
+	}
+	if got != want {
+		t.Errorf("Quadpix.Stats() = %+v, want %+v", got, want)
+	}
+	if got.Duplication() != 2 {
+		t.Errorf("Stats.Duplication() = %v, want %v", got.Duplication(), 2)
+	}
+
+	nodes := <-tree.Nodes()
+	if len(nodes) != 5 || nodes[0].Leaf || nodes[0].Bounds != tree.rect || !nodes[1].Leaf || nodes[1].Depth != 1 {
+		t.Errorf("Quadpix.Nodes() = %+v, want root followed by 4 leafs", nodes)
+	}
+}
+
+func TestQuadpix_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(q *Quadpix)
+		wantErr error
+	}{
+		{
+			name:    "valid tree",
+			corrupt: func(q *Quadpix) {},
+			wantErr: nil,
+		},
+		{
+			name: "entity missing from leaf",
+			corrupt: func(q *Quadpix) {
+				// entity 3 is stored in every leaf
+				for i, e := range q.children[1].entities {
+					if e.ID == 3 {
+						q.children[1].entities = append(q.children[1].entities[:i], q.children[1].entities[i+1:]...)
+						break
+					}
+				}
+			},
+			wantErr: ErrInvalidTree,
+		},
+		{
+			name: "entity in wrong leaf",
+			corrupt: func(q *Quadpix) {
+				q.children[3].entities = append(q.children[3].entities, q.children[0].entities[0])
+			},
+			wantErr: ErrInvalidTree,
+		},
+		{
+			name: "branch holding entities",
+			corrupt: func(q *Quadpix) {
+				q.entities = append(q.entities, q.children[0].entities[0])
+			},
+			wantErr: ErrInvalidTree,
+		},
+		{
+			name: "too deep",
+			corrupt: func(q *Quadpix) {
+				q.maxDepth = 0
+			},
+			wantErr: ErrInvalidTree,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := New(800, 600, 2, 4)
+			tree.InsertEntities(
+				&Entity{ID: 1, Rect: pixel.R(10, 10, 50, 50)},
+				&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+				&Entity{ID: 3, Rect: pixel.R(350, 250, 450, 350)},
+			)
+
+			tt.corrupt(tree)
+
+			if err := <-tree.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Quadpix.Validate() got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package quadpix
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+)
+
+// Tx is a batch of Insert, Remove and Update operations that are applied to the tree as a unit.
+//
+// Operations are only staged until Commit is called. Commit applies them all in order and if any
+// of them fails the tree is restored to the state it was in before the commit.
+type Tx struct {
+	tree *Quadpix
+
+	commands []command
+	done     bool
+}
+
+// Begin starts a new transaction on the tree.
+func (q *Quadpix) Begin() *Tx {
+	return &Tx{
+		tree: q,
+	}
+}
+
+// Insert stages the insert of a new Entity with the given pixel.Rect bounds and Action functions.
+//
+// The created entity is returned so it can be referenced by later operations.
+func (tx *Tx) Insert(rect pixel.Rect, action ...Action) *Entity {
+	entity := E(rect, action...)
+	tx.commands = append(tx.commands, command{op: OpInsert, entity: entity})
+	return entity
+}
+
+// InsertEntities stages the insert of any number of Entity's.
+//
+// This function will return an error if no entities are given to InsertEntities.
+func (tx *Tx) InsertEntities(entities ...*Entity) error {
+	// Check for no entities given.
+	if len(entities) == 0 {
+		return ErrNoEntitiesGiven
+	}
+
+	for _, e := range entities {
+		tx.commands = append(tx.commands, command{op: OpInsert, entity: e})
+	}
+
+	return nil
+}
+
+// Remove stages the removal of the given entity.
+func (tx *Tx) Remove(entity *Entity) {
+	tx.commands = append(tx.commands, command{op: OpRemove, entity: entity})
+}
+
+// Update stages moving the given entity to the new pixel.Rect bounds.
+func (tx *Tx) Update(entity *Entity, rect pixel.Rect) {
+	tx.commands = append(tx.commands, command{op: OpUpdate, entity: entity, rect: rect})
+}
+
+// Commit applies all staged operations to the tree in order.
+//
+// The bounds of every insert and update are checked before the tree is changed, and Commit returns a
+// *CommandError wrapping ErrInvalidBounds for the first one the tree can not hold. If an operation fails
+// while being applied Commit undoes the operations applied before it, so the tree holds the same entities
+// with the same bounds as before Commit was called, and returns a *CommandError for the failed operation.
+// A transaction can only be committed once, after that Commit returns ErrTxDone.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	// check the bounds of every operation before changing the tree
+	for i, c := range tx.commands {
+		rect := c.rect
+		if c.op == OpInsert {
+			rect = c.entity.Rect
+		}
+
+		if c.op != OpRemove && !tx.tree.accepts(rect) {
+			return &CommandError{
+				Index:  i,
+				Op:     c.op,
+				Entity: c.entity,
+				Err:    fmt.Errorf("%w: entity %v at %v", ErrInvalidBounds, c.entity.ID, rect),
+			}
+		}
+	}
+
+	// stop the history from recording each operation on its own
+	history := tx.tree.history
+	recording := history != nil && !history.paused
+	if recording {
+		history.paused = true
+		defer func() {
+			history.paused = false
+		}()
+	}
+
+	// only send the events of the transaction once it is known to succeed
+	held := tx.tree.hold()
+	committed := false
+	defer func() {
+		tx.tree.release(held, committed)
+	}()
+
+	// save the dirty regions and split count so they can be restored on error
+	dirty := append([]pixel.Rect(nil), tx.tree.dirty...)
+	splits := tx.tree.splits
+	records := make([]record, 0, len(tx.commands))
+
+	for i, c := range tx.commands {
+		rec := record{op: c.op, entity: c.entity}
+		switch c.op {
+		case OpInsert:
+			rec.to = c.entity.Rect
+		case OpRemove:
+			rec.from = c.entity.Rect
+		case OpUpdate:
+			rec.from, rec.to = c.entity.Rect, c.rect
+		}
+
+		if err := tx.tree.apply(c); err != nil {
+			// undo the applied operations and restore the tree's state
+			tx.undo(records)
+			tx.tree.dirty = dirty
+			tx.tree.splits = splits
+
+			return &CommandError{
+				Index:  i,
+				Op:     c.op,
+				Entity: c.entity,
+				Err:    err,
+			}
+		}
+
+		records = append(records, rec)
+	}
+
+	// record the whole transaction as a single history step
+	if recording {
+		history.push(records)
+	}
+	committed = true
+
+	return nil
+}
+
+// undo reverts the given applied operations in reverse order.
+//
+// The reverted operations are written to the recording so it still matches the tree, but are not
+// counted as operations or added to the history.
+func (tx *Tx) undo(records []record) {
+	q := tx.tree
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+
+		// operations that were just applied can always be reverted
+		switch r.op {
+		case OpInsert:
+			if err := q.remove(r.entity); err != nil {
+				panic(err)
+			}
+		case OpRemove:
+			r.entity.Rect = r.from
+			q.insert(r.entity)
+		case OpUpdate:
+			if err := q.remove(r.entity); err != nil {
+				panic(err)
+			}
+			r.entity.Rect = r.from
+			q.insert(r.entity)
+		}
+
+		q.metrics.undo(r.op)
+		q.recorder.mutation(r.inverse())
+	}
+}
+
+// Rollback discards all staged operations without changing the tree.
+//
+// After Rollback the transaction can no longer be committed.
+func (tx *Tx) Rollback() {
+	tx.commands = nil
+	tx.done = true
+}
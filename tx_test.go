@@ -0,0 +1,208 @@
+package quadpix
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestTx_Commit(t *testing.T) {
+	type fields struct {
+		quadpix  *Quadpix
+		entities Entities
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		stage   func(tx *Tx, entities Entities) (in, out Entities)
+		wantErr error
+	}{
+		{
+			name: "commit insert remove and update",
+			fields: fields{
+				quadpix: New(800, 600, 1, 4),
+				entities: Entities{
+					&Entity{ID: 1, Rect: pixel.R(0, 0, 50, 50)},
+					&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+				},
+			},
+			stage: func(tx *Tx, entities Entities) (in, out Entities) {
+				inserted := tx.Insert(pixel.R(100, 100, 150, 150))
+				tx.Remove(entities[0])
+				tx.Update(entities[1], pixel.R(10, 10, 20, 20))
+				return Entities{inserted, entities[1]}, Entities{entities[0]}
+			},
+			wantErr: nil,
+		},
+		{
+			name: "rollback on remove error",
+			fields: fields{
+				quadpix: New(800, 600, 1, 4),
+				entities: Entities{
+					&Entity{ID: 1, Rect: pixel.R(0, 0, 50, 50)},
+					&Entity{ID: 2, Rect: pixel.R(500, 400, 550, 450)},
+				},
+			},
+			stage: func(tx *Tx, entities Entities) (in, out Entities) {
+				inserted := tx.Insert(pixel.R(100, 100, 150, 150))
+				tx.Remove(entities[0])
+				tx.Update(entities[1], pixel.R(10, 10, 20, 20))
+				tx.Remove(&Entity{ID: 5, Rect: pixel.R(300, 300, 310, 310)})
+				return Entities{entities[0], entities[1]}, Entities{inserted}
+			},
+			wantErr: ErrNoEntityFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fields.quadpix.InsertEntities(tt.fields.entities...); err != nil {
+				t.Errorf("Tx.Commit() got error on insert %v", err)
+			}
+
+			old := make([]pixel.Rect, len(tt.fields.entities))
+			for i, e := range tt.fields.entities {
+				old[i] = e.Rect
+			}
+
+			tx := tt.fields.quadpix.Begin()
+			in, out := tt.stage(tx, tt.fields.entities)
+
+			err := tx.Commit()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Tx.Commit() got an unwanted error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				for i, e := range tt.fields.entities {
+					if e.Rect != old[i] {
+						t.Errorf("Tx.Commit() did not restore bounds of %v, want %v", e, old[i])
+					}
+				}
+			}
+
+			for _, e := range in {
+				if !<-tt.fields.quadpix.IsEntity(e) {
+					t.Errorf("Tx.Commit() entity not in tree %v", e)
+				}
+			}
+			for _, e := range out {
+				if <-tt.fields.quadpix.IsEntity(e) {
+					t.Errorf("Tx.Commit() entity in tree %v", e)
+				}
+			}
+
+			if err := tx.Commit(); err != ErrTxDone {
+				t.Errorf("Tx.Commit() second commit got error %v, want %v", err, ErrTxDone)
+			}
+		})
+	}
+}
+
+func TestTx_Rollback(t *testing.T) {
+	tree := New(800, 600, 10, 4)
+
+	tx := tree.Begin()
+	e := tx.Insert(pixel.R(0, 0, 10, 10))
+	tx.Rollback()
+
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("Tx.Commit() after rollback got error %v, want %v", err, ErrTxDone)
+	}
+	if <-tree.IsEntity(e) {
+		t.Errorf("Tx.Rollback() entity in tree %v", e)
+	}
+}
+
+func TestTx_CommitRestoresDirty(t *testing.T) {
+	q := New(800, 600, 1, 4)
+	e := E(pixel.R(0, 0, 50, 50))
+	if err := q.InsertEntities(e); err != nil {
+		t.Fatal(err)
+	}
+	q.EnableDirtyTracking()
+	splits := q.splits
+
+	tx := q.Begin()
+	tx.Insert(pixel.R(100, 100, 150, 150))
+	tx.Update(e, pixel.R(600, 400, 650, 450))
+	tx.Remove(&Entity{ID: 5, Rect: pixel.R(300, 300, 310, 310)})
+	if err := tx.Commit(); !errors.Is(err, ErrNoEntityFound) {
+		t.Fatalf("Tx.Commit() got error %v, want %v", err, ErrNoEntityFound)
+	}
+
+	if got := q.DirtyRegions(); len(got) != 0 {
+		t.Errorf("Tx.Commit() left dirty regions %v after rolling back", got)
+	}
+	if q.splits != splits {
+		t.Errorf("Tx.Commit() left %d splits after rolling back, want %d", q.splits, splits)
+	}
+}
+
+func TestTx_CommitOutOfBounds(t *testing.T) {
+	q := New(800, 600, 1, 4)
+	a := E(pixel.R(0, 0, 50, 50))
+	b := E(pixel.R(500, 400, 550, 450))
+	if err := q.InsertEntities(a, b); err != nil {
+		t.Fatal(err)
+	}
+	m := q.EnableMetrics()
+	before := <-q.Stats()
+
+	// the tree has split so bounds outside the root can not be stored in any node
+	tx := q.Begin()
+	tx.Update(a, pixel.R(100, 100, 150, 150))
+	tx.Remove(b)
+	outside := tx.Insert(pixel.R(900, 700, 950, 750))
+
+	var cmdErr *CommandError
+	err := tx.Commit()
+	if !errors.As(err, &cmdErr) || cmdErr.Index != 2 || !errors.Is(err, ErrInvalidBounds) {
+		t.Fatalf("Tx.Commit() got error %v, want %v for command 2", err, ErrInvalidBounds)
+	}
+
+	if got := <-q.Stats(); got != before {
+		t.Errorf("Tx.Commit() changed the tree to %v, want %v", got, before)
+	}
+	if a.Rect != pixel.R(0, 0, 50, 50) || !<-q.IsEntity(a) || !<-q.IsEntity(b) || q.all().Contains(outside) {
+		t.Errorf("Tx.Commit() changed the entities of the tree")
+	}
+	if s := m.Snapshot(); s.Updates != 0 || s.Removes != 0 || s.Entities != 2 {
+		t.Errorf("Tx.Commit() counted operations %+v", s)
+	}
+}
+
+func TestTx_CommitUndo(t *testing.T) {
+	q := New(800, 600, 1, 4)
+	a := E(pixel.R(0, 0, 50, 50))
+	b := E(pixel.R(500, 400, 550, 450))
+	if err := q.InsertEntities(a, b); err != nil {
+		t.Fatal(err)
+	}
+	m := q.EnableMetrics()
+
+	// the applied operations are undone in reverse order
+	tx := q.Begin()
+	inserted := tx.Insert(pixel.R(100, 100, 150, 150))
+	tx.Update(a, pixel.R(600, 100, 650, 150))
+	tx.Update(a, pixel.R(600, 500, 650, 550))
+	tx.Remove(b)
+	tx.Remove(b)
+	if err := tx.Commit(); !errors.Is(err, ErrNoEntityFound) {
+		t.Fatalf("Tx.Commit() got error %v, want %v", err, ErrNoEntityFound)
+	}
+
+	got := q.all()
+	if len(got) != 2 || !got.Contains(a) || !got.Contains(b) || a.Rect != pixel.R(0, 0, 50, 50) {
+		t.Errorf("Tx.Commit() left entities %v after rolling back", got)
+	}
+	if !<-q.IsEntity(a) || !<-q.IsEntity(b) || <-q.IsEntity(inserted) {
+		t.Errorf("Tx.Commit() left the entities in the wrong nodes")
+	}
+	if err := <-q.Validate(); err != nil {
+		t.Error(err)
+	}
+	if got := m.Snapshot().Entities; got != 2 {
+		t.Errorf("Metrics.Snapshot().Entities after rollback = %d, want 2", got)
+	}
+}
@@ -0,0 +1,430 @@
+package quadpix
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/faiface/pixel"
+)
+
+// World is an unbounded space split in to chunks of a fixed size, each chunk backed by its own Quadpix.
+//
+// Chunks are created the first time an entity is inserted in to them, so a World never needs to know the
+// size of the space up front. Each entity is stored in every chunk it intersects and queries that cross
+// chunk boundaries merge the results of every chunk they reach without duplicates. Entities spanning more
+// then maxWorldChunks chunks are kept in a separate list checked by every query.
+//
+// The Quadpix of each chunk is created with the maxEntities, maxDepth and Options given to NewWorld and
+// covers the bounds of the chunk, starting at the chunk's ChunkKey times the chunk size.
+//
+// World implements SpatialIndex and follows the same rules as Quadpix.
+type World struct {
+	chunkSize pixel.Vec
+	config
+
+	chunks map[ChunkKey]*Quadpix
+	large  Entities
+	count  int
+}
+
+// ChunkKey is the position of a chunk in a World, in chunks from the origin.
+type ChunkKey struct {
+	X, Y int64
+}
+
+// maxWorldChunks is the most chunks an entity is stored in before it is kept in the World's list of large entities.
+const maxWorldChunks = 64
+
+var _ SpatialIndex = (*World)(nil)
+
+// NewWorld creates a new empty World with chunks of the given width and height.
+//
+// The maxEntities, maxDepth and Options are used for the Quadpix of every chunk, the same as they are by New.
+// NewWorld panics if the width or height is not a finite number greater then 0.
+func NewWorld(chunkWidth, chunkHeight float64, maxEntities uint64, maxDepth uint16, opts ...Option) *World {
+	for _, size := range []float64{chunkWidth, chunkHeight} {
+		if !(size > 0) || math.IsInf(size, 0) {
+			panic(fmt.Sprintf("quadpix: invalid world chunk size %v x %v", chunkWidth, chunkHeight))
+		}
+	}
+
+	cfg := config{
+		maxEntities: maxEntities,
+		maxDepth:    maxDepth,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &World{
+		chunkSize: pixel.V(chunkWidth, chunkHeight),
+		config:    cfg,
+		chunks:    make(map[ChunkKey]*Quadpix),
+	}
+}
+
+// ChunkSize returns the width and height of the World's chunks.
+func (w *World) ChunkSize() pixel.Vec {
+	return w.chunkSize
+}
+
+// Len returns the number of entities in the World.
+func (w *World) Len() int {
+	return w.count
+}
+
+// Chunks returns the keys of every chunk that has been created, sorted by row and then column.
+func (w *World) Chunks() []ChunkKey {
+	keys := make([]ChunkKey, 0, len(w.chunks))
+	for k := range w.chunks {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Y != keys[j].Y {
+			return keys[i].Y < keys[j].Y
+		}
+		return keys[i].X < keys[j].X
+	})
+
+	return keys
+}
+
+// Chunk returns the Quadpix backing the chunk with the given key, or nil if the chunk has not been created.
+//
+// The Quadpix can be used to draw, inspect or observe the chunk, but entities must be changed through the
+// World so they are kept in every chunk they intersect.
+func (w *World) Chunk(key ChunkKey) *Quadpix {
+	return w.chunks[key]
+}
+
+// ChunkAt returns the key of the chunk holding the given point.
+//
+// Points on the edge between two chunks belong to the right or top chunk.
+func (w *World) ChunkAt(point pixel.Vec) ChunkKey {
+	return ChunkKey{
+		X: int64(math.Floor(point.X / w.chunkSize.X)),
+		Y: int64(math.Floor(point.Y / w.chunkSize.Y)),
+	}
+}
+
+// ChunkRect returns the bounds of the chunk with the given key.
+func (w *World) ChunkRect(key ChunkKey) pixel.Rect {
+	min := pixel.V(float64(key.X)*w.chunkSize.X, float64(key.Y)*w.chunkSize.Y)
+	return pixel.Rect{Min: min, Max: min.Add(w.chunkSize)}
+}
+
+// Prune drops every chunk holding no entities and returns the number of chunks dropped.
+func (w *World) Prune() int {
+	pruned := 0
+	for k, c := range w.chunks {
+		if len(c.all()) == 0 {
+			delete(w.chunks, k)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// Insert adds the given pixel.Rect to the World as an entity bound with the given Actions.
+func (w *World) Insert(rect pixel.Rect, action ...Action) {
+	w.insert(E(rect, action...))
+}
+
+// InsertEntities inserts any number of Entity's in to the World.
+//
+// Like Quadpix, entities that have Behaviours but no Actions get their Actions bound from their Behaviours first.
+// InsertEntities returns ErrNoEntitiesGiven if no entities are given, and on error no entities are inserted.
+func (w *World) InsertEntities(entities ...*Entity) error {
+	if len(entities) == 0 {
+		return ErrNoEntitiesGiven
+	}
+
+	if err := bindEntities(entities); err != nil {
+		return err
+	}
+
+	for _, e := range entities {
+		w.insert(e)
+	}
+
+	return nil
+}
+
+// Remove the given entity from every chunk of the World it is stored in.
+//
+// Remove returns ErrNoEntityFound if no entity with the same ID and pixel.Rect is in the World.
+// Chunks left empty are kept until Prune is called.
+func (w *World) Remove(entity *Entity) error {
+	return w.remove(entity)
+}
+
+// Update moves the given entity to the new pixel.Rect bounds within the World, moving it between chunks as needed.
+//
+// Update returns ErrNoEntityFound if the entity is not in the World. On success the given entity's Rect is set to the new bounds.
+func (w *World) Update(entity *Entity, rect pixel.Rect) error {
+	if err := w.remove(entity); err != nil {
+		return err
+	}
+
+	entity.Rect = rect
+	w.insert(entity)
+
+	return nil
+}
+
+// Retrieve gets all entities from every chunk the given pixel.Rect reaches without duplicates, along with all large entities.
+//
+// Retrieve returns a channel of entities as all Read-Only operations are run on there own thread.
+func (w *World) Retrieve(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		out <- w.retrieve(rect)
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersect returns whether or not the given pixel.Rect intersects any entity with in the World.
+//
+// Intersect returns a channel of a bool as all Read-Only operations are run on there own thread.
+func (w *World) Intersect(rect pixel.Rect) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		out <- w.intersect(rect)
+		close(out)
+	}()
+
+	return out
+}
+
+// Intersects returns a channel of all entities that intersect with the given pixel.Rect within the World.
+//
+// Intersects returns a channel of Entities as all Read-Only operations are run on there own thread.
+func (w *World) Intersects(rect pixel.Rect) <-chan Entities {
+	out := make(chan Entities)
+
+	go func() {
+		out <- w.retrieve(rect).IntersectsWith(rect, w.overlap)
+		close(out)
+	}()
+
+	return out
+}
+
+// IsEntity returns whether or not the given entity exists with in the World.
+//
+// IsEntity returns a channel holding a bool as all Read-Only operations are run on there own thread.
+// The given entity must have the same ID and pixel.Rect bounds to be found.
+func (w *World) IsEntity(entity *Entity) <-chan bool {
+	out := make(chan bool)
+
+	go func() {
+		out <- w.isEntity(entity)
+		close(out)
+	}()
+
+	return out
+}
+
+// span returns the first and last chunk the given pixel.Rect covers and the number of chunks between them.
+//
+// ok is false if the pixel.Rect is not valid or is too far from the origin to be stored in chunks.
+// With clamp the span is cut to the chunks that can be stored instead, so queries reaching past them
+// still find every stored chunk they cover.
+func (w *World) span(rect pixel.Rect, clamp bool) (min, max ChunkKey, chunks float64, ok bool) {
+	x0, y0 := math.Floor(rect.Min.X/w.chunkSize.X), math.Floor(rect.Min.Y/w.chunkSize.Y)
+	x1, y1 := math.Floor(rect.Max.X/w.chunkSize.X), math.Floor(rect.Max.Y/w.chunkSize.Y)
+
+	if clamp {
+		x0, y0 = math.Max(x0, -maxGridIndex), math.Max(y0, -maxGridIndex)
+		x1, y1 = math.Min(x1, maxGridIndex), math.Min(y1, maxGridIndex)
+	}
+
+	for _, f := range []float64{x0, y0, x1, y1} {
+		if !(math.Abs(f) < maxGridIndex || clamp && math.Abs(f) == maxGridIndex) {
+			return min, max, 0, false
+		}
+	}
+	if x0 > x1 || y0 > y1 {
+		return min, max, 0, false
+	}
+
+	return ChunkKey{int64(x0), int64(y0)}, ChunkKey{int64(x1), int64(y1)}, (x1 - x0 + 1) * (y1 - y0 + 1), true
+}
+
+// reach returns the bounds of the chunks a query for the given pixel.Rect has to check.
+//
+// With an inclusive epsilon entities in the chunks up to epsilon away from the query bounds can still intersect it.
+func (w *World) reach(rect pixel.Rect) pixel.Rect {
+	if e := w.overlap.Epsilon; e > 0 && w.overlap.Edges == EdgesInclusive {
+		return pixel.R(rect.Min.X-e, rect.Min.Y-e, rect.Max.X+e, rect.Max.Y+e)
+	}
+	return rect
+}
+
+// chunk returns the Quadpix of the chunk with the given key, creating it if it does not exist yet.
+func (w *World) chunk(key ChunkKey) *Quadpix {
+	c, ok := w.chunks[key]
+	if !ok {
+		c = newQuadpix(w.ChunkRect(key), w.config)
+		w.chunks[key] = c
+	}
+	return c
+}
+
+// insert stores the given entity in every chunk it covers, or in the list of large entities.
+func (w *World) insert(entity *Entity) {
+	w.count++
+
+	min, max, chunks, ok := w.span(entity.Rect, false)
+	if !ok || chunks > maxWorldChunks {
+		w.large = append(w.large, entity)
+		return
+	}
+
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			// an entity is always given and its Behaviours are bound before it reaches the chunks,
+			// so inserting it can not fail
+			if err := w.chunk(ChunkKey{x, y}).InsertEntities(entity); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// remove removes the given entity from every chunk it covers.
+func (w *World) remove(entity *Entity) error {
+	min, max, chunks, ok := w.span(entity.Rect, false)
+	if !ok || chunks > maxWorldChunks {
+		large, err := w.large.Remove(entity)
+		if err != nil {
+			return err
+		}
+		w.large = large
+		w.count--
+		return nil
+	}
+
+	// check every chunk before changing any, so the entity is never left in only some of its chunks
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			c, ok := w.chunks[ChunkKey{x, y}]
+			if !ok || !c.isEntity(entity, nil) {
+				return ErrNoEntityFound
+			}
+		}
+	}
+
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			// the entity was found in every chunk above, so removing it can not fail
+			if err := w.chunks[ChunkKey{x, y}].Remove(entity); err != nil {
+				panic(err)
+			}
+		}
+	}
+	w.count--
+
+	return nil
+}
+
+// retrieve gets all entities in the chunks the given pixel.Rect reaches without duplicates, along with all large entities.
+func (w *World) retrieve(rect pixel.Rect) Entities {
+	entities := append(Entities(nil), w.large...)
+
+	min, max, chunks, ok := w.span(w.reach(rect), true)
+	if !ok {
+		return entities
+	}
+
+	// an entity in many chunks must only be returned once
+	var seen map[*Entity]struct{}
+	if chunks > 1 {
+		seen = make(map[*Entity]struct{})
+	}
+	add := func(c *Quadpix) {
+		// every entity of a chunk the query covers is returned, which is far cheaper to collect then to retrieve
+		found := c.all()
+		if !encloses(rect, c.rect) {
+			found = c.retrieve(rect, nil)
+		}
+
+		for _, e := range found {
+			if seen != nil {
+				if _, ok := seen[e]; ok {
+					continue
+				}
+				seen[e] = struct{}{}
+			}
+			entities = append(entities, e)
+		}
+	}
+
+	// large queries are cheaper to run over the created chunks then over every chunk they cover
+	if chunks > float64(len(w.chunks)) {
+		for k, c := range w.chunks {
+			if k.X >= min.X && k.X <= max.X && k.Y >= min.Y && k.Y <= max.Y {
+				add(c)
+			}
+		}
+		return entities
+	}
+
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			if c, ok := w.chunks[ChunkKey{x, y}]; ok {
+				add(c)
+			}
+		}
+	}
+
+	return entities
+}
+
+// intersect checks if the given pixel.Rect intersects any entity in the World.
+func (w *World) intersect(rect pixel.Rect) bool {
+	if w.large.IntersectWith(rect, w.overlap) {
+		return true
+	}
+
+	min, max, chunks, ok := w.span(w.reach(rect), true)
+	if !ok {
+		return false
+	}
+
+	if chunks > float64(len(w.chunks)) {
+		for k, c := range w.chunks {
+			if k.X >= min.X && k.X <= max.X && k.Y >= min.Y && k.Y <= max.Y && c.intersect(rect, nil) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			if c, ok := w.chunks[ChunkKey{x, y}]; ok && c.intersect(rect, nil) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isEntity checks if the given entity is stored in the World.
+func (w *World) isEntity(entity *Entity) bool {
+	min, _, chunks, ok := w.span(entity.Rect, false)
+	if !ok || chunks > maxWorldChunks {
+		return w.large.Contains(entity)
+	}
+
+	c, ok := w.chunks[min]
+	return ok && c.isEntity(entity, nil)
+}
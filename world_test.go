@@ -0,0 +1,179 @@
+package quadpix
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestNewWorld(t *testing.T) {
+	for _, size := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewWorld(%v) did not panic", size)
+				}
+			}()
+			NewWorld(size, 100, 8, 4)
+		}()
+	}
+}
+
+func TestWorld_Chunks(t *testing.T) {
+	w := NewWorld(100, 100, 8, 4)
+	if len(w.Chunks()) != 0 {
+		t.Fatalf("Chunks() = %v, want none", w.Chunks())
+	}
+
+	// one entity in a single chunk and one crossing the corner of four chunks
+	a := E(pixel.R(-50, 10, -40, 20))
+	b := E(pixel.R(190, 290, 210, 310))
+	if err := w.InsertEntities(a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ChunkKey{{-1, 0}, {1, 2}, {2, 2}, {1, 3}, {2, 3}}
+	if got := w.Chunks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunks() = %v, want %v", got, want)
+	}
+	for _, k := range want[1:] {
+		if !<-w.Chunk(k).IsEntity(b) {
+			t.Errorf("chunk %v does not hold %v", k, b)
+		}
+	}
+
+	// queries never create chunks
+	<-w.Retrieve(pixel.R(1000, 1000, 1100, 1100))
+	<-w.Intersect(pixel.R(-1000, -1000, -900, -900))
+	if got := len(w.Chunks()); got != len(want) {
+		t.Errorf("queries created chunks, have %d want %d", got, len(want))
+	}
+	if w.Chunk(ChunkKey{5, 5}) != nil {
+		t.Error("Chunk() returned a chunk that was never created")
+	}
+
+	if got := w.ChunkAt(pixel.V(-50, 200)); got != (ChunkKey{-1, 2}) {
+		t.Errorf("ChunkAt() = %v", got)
+	}
+	if got := w.ChunkAt(pixel.V(100, 100)); got != (ChunkKey{1, 1}) {
+		t.Errorf("ChunkAt() on an edge = %v", got)
+	}
+	if got := w.ChunkRect(ChunkKey{-1, 2}); got != pixel.R(-100, 200, 0, 300) {
+		t.Errorf("ChunkRect() = %v", got)
+	}
+	if got := w.Chunk(ChunkKey{-1, 0}).rect; got != w.ChunkRect(ChunkKey{-1, 0}) {
+		t.Errorf("chunk bounds = %v, want %v", got, w.ChunkRect(ChunkKey{-1, 0}))
+	}
+}
+
+func TestWorld_Queries(t *testing.T) {
+	w := NewWorld(100, 100, 1, 4)
+	a := E(pixel.R(10, 10, 20, 20))
+	b := E(pixel.R(90, 90, 110, 110))
+	c := E(pixel.R(-1e9, -1e9, 1e9, 1e9))
+	if err := w.InsertEntities(a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-w.Retrieve(pixel.R(0, 0, 200, 200))
+	if len(got) != 2 || !got.Contains(a) || !got.Contains(b) {
+		t.Errorf("Retrieve() = %v, want %v and %v once each", got, a, b)
+	}
+	if got := <-w.Intersects(pixel.R(105, 105, 150, 150)); !reflect.DeepEqual(got, Entities{b}) {
+		t.Errorf("Intersects() = %v, want %v", got, Entities{b})
+	}
+	if <-w.Intersect(pixel.R(150, 150, 160, 160)) {
+		t.Error("Intersect() found an entity in an empty chunk")
+	}
+
+	// entities covering too many chunks are kept out of the chunks
+	if err := w.InsertEntities(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Chunks()) != 4 || !<-w.IsEntity(c) || !<-w.Intersect(pixel.R(5000, 5000, 5001, 5001)) {
+		t.Errorf("large entity was not stored apart from the chunks, chunks %v", w.Chunks())
+	}
+	if w.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", w.Len())
+	}
+}
+
+func TestWorld_WideQueries(t *testing.T) {
+	w := NewWorld(100, 100, 8, 4)
+	e := E(pixel.R(10, 10, 20, 20))
+	if err := w.InsertEntities(e); err != nil {
+		t.Fatal(err)
+	}
+
+	// queries spanning more chunks then can be stored still find every created chunk
+	for _, query := range []pixel.Rect{
+		pixel.R(-1e19, -1e19, 1e19, 1e19),
+		pixel.R(-1e21, -1e21, 1e21, 1e21),
+		pixel.R(math.Inf(-1), math.Inf(-1), math.Inf(1), math.Inf(1)),
+	} {
+		if got := <-w.Retrieve(query); len(got) != 1 {
+			t.Errorf("Retrieve(%v) = %v, want %v", query, got, e)
+		}
+		if got := <-w.Intersects(query); len(got) != 1 {
+			t.Errorf("Intersects(%v) = %v, want %v", query, got, e)
+		}
+		if !<-w.Intersect(query) {
+			t.Errorf("Intersect(%v) = false, want true", query)
+		}
+	}
+
+	if got := <-w.Retrieve(pixel.R(1e21, 1e21, 2e21, 2e21)); len(got) != 0 {
+		t.Errorf("Retrieve() past every chunk = %v, want none", got)
+	}
+}
+
+func TestWorld_Update(t *testing.T) {
+	w := NewWorld(100, 100, 8, 4)
+	e := E(pixel.R(10, 10, 20, 20))
+	if err := w.InsertEntities(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Update(e, pixel.R(-20, 150, -10, 160)); err != nil {
+		t.Fatal(err)
+	}
+	if <-w.Chunk(ChunkKey{0, 0}).IsEntity(e) || !<-w.Chunk(ChunkKey{-1, 1}).IsEntity(e) {
+		t.Error("Update() did not move the entity between chunks")
+	}
+
+	if err := w.Update(&Entity{ID: e.ID, Rect: pixel.R(10, 10, 20, 20)}, pixel.R(0, 0, 1, 1)); err != ErrNoEntityFound {
+		t.Errorf("Update() error = %v, want %v", err, ErrNoEntityFound)
+	}
+
+	// an entity missing from one of its chunks is not removed from any of them
+	f := E(pixel.R(290, 10, 310, 20))
+	if err := w.InsertEntities(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Chunk(ChunkKey{3, 0}).Remove(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Remove(f); err != ErrNoEntityFound {
+		t.Errorf("Remove() error = %v, want %v", err, ErrNoEntityFound)
+	}
+	if !<-w.Chunk(ChunkKey{2, 0}).IsEntity(f) {
+		t.Error("Remove() changed a chunk before failing")
+	}
+	w.Chunk(ChunkKey{3, 0}).InsertEntities(f)
+	if err := w.Remove(f); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := w.Prune(); got != 3 || w.Chunk(ChunkKey{0, 0}) != nil {
+		t.Errorf("Prune() = %d, want 3 with chunk {0 0} dropped", got)
+	}
+
+	if err := w.Remove(e); err != nil {
+		t.Fatal(err)
+	}
+	if w.Len() != 0 || w.Prune() != 1 || len(w.Chunks()) != 0 {
+		t.Errorf("Len() = %d, chunks %v after removing every entity", w.Len(), w.Chunks())
+	}
+}